@@ -0,0 +1,235 @@
+package multiwriter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// AggFunc aggregates the numeric values of a column into a single value
+type AggFunc func(values []float64) float64
+
+var (
+	// Sum adds up the values
+	Sum AggFunc = func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	}
+	// Avg averages the values
+	Avg AggFunc = func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		return Sum(values) / float64(len(values))
+	}
+	// Min returns the smallest value
+	Min AggFunc = func(values []float64) float64 {
+		var min float64
+		for i, v := range values {
+			if i == 0 || v < min {
+				min = v
+			}
+		}
+		return min
+	}
+	// Max returns the largest value
+	Max AggFunc = func(values []float64) float64 {
+		var max float64
+		for i, v := range values {
+			if i == 0 || v > max {
+				max = v
+			}
+		}
+		return max
+	}
+	// Count counts the values
+	Count AggFunc = func(values []float64) float64 {
+		return float64(len(values))
+	}
+)
+
+// subtotals describes how rows are grouped and aggregated into subtotal rows
+type subtotals struct {
+	groupCol   string
+	numericCol string
+	agg        AggFunc
+}
+
+// parseNumbers returns the values that parse as numbers
+func parseNumbers(values []string) []float64 {
+	nums := make([]float64, 0, len(values))
+	for _, v := range values {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err == nil {
+			nums = append(nums, f)
+		}
+	}
+	return nums
+}
+
+// formatNumber renders an aggregated value
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// insertSubtotals sorts rows by the group column and inserts a subtotal row
+// after each group, returning the new rows and the grand total footer
+func (w *Writer) insertSubtotals(rows []row) ([]row, []string) {
+	gi := w.columnIndex(w.subtotals.groupCol)
+	ni := w.columnIndex(w.subtotals.numericCol)
+	if gi < 0 || ni < 0 {
+		return rows, nil
+	}
+	records := rows[:0]
+	for _, r := range rows {
+		if !r.separator {
+			records = append(records, r)
+		}
+	}
+	rows = records
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].raw[gi] < rows[j].raw[gi]
+	})
+	aggRow := func(label string, values []string) row {
+		r := row{values: make([]string, len(w.columns))}
+		r.values[gi] = label
+		r.values[ni] = formatNumber(w.subtotals.agg(parseNumbers(values)))
+		return r
+	}
+	var out []row
+	var group, all []string
+	for i, r := range rows {
+		out = append(out, r)
+		group = append(group, r.raw[ni])
+		all = append(all, r.raw[ni])
+		if i == len(rows)-1 || rows[i+1].raw[gi] != r.raw[gi] {
+			out = append(out, aggRow(r.raw[gi]+" subtotal", group))
+			group = nil
+		}
+	}
+	return out, aggRow("Total", all).values
+}
+
+// pivot describes how records are reshaped into a pivot table
+type pivot struct {
+	rowCol   string
+	colCol   string
+	valueCol string
+	agg      AggFunc
+}
+
+// pivotRows reshapes rows into a pivot with the distinct values of the row
+// column as rows and the distinct values of the column column as columns,
+// returning the pivot's header and records
+func (w *Writer) pivotRows(rows []row) ([]string, [][]string) {
+	ri := w.columnIndex(w.pivot.rowCol)
+	ci := w.columnIndex(w.pivot.colCol)
+	vi := w.columnIndex(w.pivot.valueCol)
+	header := []string{w.pivot.rowCol}
+	if ri < 0 || ci < 0 || vi < 0 {
+		return header, nil
+	}
+	cells := map[[2]string][]string{}
+	var rowKeys, colKeys []string
+	seenRows, seenCols := map[string]bool{}, map[string]bool{}
+	for _, r := range rows {
+		if r.separator {
+			continue
+		}
+		rk, ck := r.values[ri], r.values[ci]
+		if !seenRows[rk] {
+			seenRows[rk] = true
+			rowKeys = append(rowKeys, rk)
+		}
+		if !seenCols[ck] {
+			seenCols[ck] = true
+			colKeys = append(colKeys, ck)
+		}
+		cells[[2]string{rk, ck}] = append(cells[[2]string{rk, ck}], r.raw[vi])
+	}
+	sort.Strings(rowKeys)
+	sort.Strings(colKeys)
+	header = append(header, colKeys...)
+	records := make([][]string, len(rowKeys))
+	for i, rk := range rowKeys {
+		record := []string{rk}
+		for _, ck := range colKeys {
+			values, ok := cells[[2]string{rk, ck}]
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, formatNumber(w.pivot.agg(parseNumbers(values))))
+		}
+		records[i] = record
+	}
+	return header, records
+}
+
+// writePivot writes the pivot of rows as a complete Table or CSV document
+func (w *Writer) writePivot(rows []row) {
+	header, records := w.pivotRows(rows)
+	if !w.noHeader {
+		records = append([][]string{header}, records...)
+	}
+	switch w.format {
+	case CSVFormat:
+		for _, record := range records {
+			if err := w.writeCSV(record); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error writing pivot to csv: %s", err))
+				return
+			}
+		}
+	case TableFormat:
+		w.table.ClearHeaders()
+		if w.noHeader {
+			w.table.AppendBulk(records)
+			break
+		}
+		w.table.SetHeader(header)
+		w.table.AppendBulk(records[1:])
+	}
+}
+
+// pivoting returns whether records are reshaped into a pivot
+func (w *Writer) pivoting() bool {
+	return w.pivot != nil && (w.format == CSVFormat || w.format == TableFormat)
+}
+
+// reduce folds the records written since the last flush into a summary record
+type reduce struct {
+	fn      func(acc, record []string) []string
+	initial []string
+	acc     []string
+	n       int
+}
+
+// reduceRecord folds the raw record into the accumulator
+func (w *Writer) reduceRecord(record []string) {
+	if w.reduce.n == 0 {
+		w.reduce.acc = append([]string(nil), w.reduce.initial...)
+	}
+	w.reduce.acc = w.reduce.fn(w.reduce.acc, record)
+	w.reduce.n++
+}
+
+// writeReduced writes the summary record of the records folded since the last
+// flush, if any, and resets the accumulator
+func (w *Writer) writeReduced() {
+	if w.reduce == nil || w.reduce.n == 0 {
+		return
+	}
+	record := w.reduce.acc
+	w.reduce.acc, w.reduce.n = nil, 0
+	if len(record) != len(w.columns) {
+		w.err = multierror.Append(w.err, fmt.Errorf("error reducing records: record has %d fields, expected %d", len(record), len(w.columns)))
+		return
+	}
+	w.writeRecord(record)
+}
@@ -0,0 +1,92 @@
+package multiwriter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSubtotals(t *testing.T) {
+	records := [][]string{{"west", "3"}, {"east", "1"}, {"west", "4"}, {"east", "x"}, {"east", "2"}}
+	got := writeRecords(t, []string{"region", "sales"}, TableFormat, records, WithSubtotals("region", "sales", Sum))
+	want := `+---------------+-------+
+|    REGION     | SALES |
++---------------+-------+
+| east          |     1 |
+| east          | x     |
+| east          |     2 |
+| east subtotal |     3 |
+| west          |     3 |
+| west          |     4 |
+| west subtotal |     7 |
++---------------+-------+
+|     TOTAL     |  10   |
++---------------+-------+
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	columns := []string{"region", "quarter", "sales"}
+	records := [][]string{
+		{"west", "q1", "3"}, {"east", "q2", "1"}, {"west", "q1", "4"}, {"east", "q1", "x"}, {"west", "q2", "2"},
+	}
+	got := writeRecords(t, columns, CSVFormat, records, WithPivot("region", "quarter", "sales", Sum))
+	if want := "region,q1,q2\neast,0,1\nwest,7,2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// each flush emits a complete pivot with its own header
+	var buf bytes.Buffer
+	w := New(&buf, columns, CSVFormat, WithPivot("region", "quarter", "sales", Max))
+	w.Write([]string{"west", "q1", "3"})
+	w.Flush()
+	w.Write([]string{"east", "q3", "5"})
+	w.Write([]string{"east", "q3", "8"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "region,q1\nwest,3\nregion,q3\neast,8\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, record []string) []string {
+		a, _ := strconv.Atoi(acc[1])
+		b, _ := strconv.Atoi(record[1])
+		return []string{acc[0], strconv.Itoa(a + b)}
+	}
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name", "n"}, CSVFormat, WithReduce(sum, []string{"total", "0"}))
+	w.Write([]string{"a", "1"})
+	w.Write([]string{"b", "2"})
+	w.Flush()
+	// nothing is written for a flush without records
+	w.Flush()
+	w.Write([]string{"c", "4"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "name,n\ntotal,3\ntotal,4\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got := writeRecords(t, []string{"name", "n"}, CSVFormat, [][]string{{"a", "1"}, {"b", "2"}},
+		WithReduce(sum, []string{"total", "0"}), WithKeepReducedRows(true))
+	if want := "name,n\na,1\nb,2\ntotal,3\n"; got != want {
+		t.Errorf("got %q with the rows kept, want %q", got, want)
+	}
+
+	w = New(ioutil.Discard, []string{"name", "n"}, CSVFormat, WithReduce(func(acc, _ []string) []string {
+		return acc[:1]
+	}, []string{"total", "0"}))
+	w.Write([]string{"a", "1"})
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "record has 1 fields, expected 2") {
+		t.Errorf("got %v, want a reduced record length error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package multiwriter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+const (
+	// ChangeColumn is the name of the column added by WithBaseline
+	ChangeColumn = "change"
+	// ChangeAdded flags a record whose key is not in the baseline
+	ChangeAdded = "added"
+	// ChangeModified flags a record whose content differs from the baseline
+	ChangeModified = "changed"
+)
+
+// baseline holds the content of a prior export keyed by the key column
+type baseline struct {
+	prior  map[string]string
+	keyCol string
+}
+
+// BaselineValue returns the canonical content of a raw record, as expected
+// in the prior export map passed to WithBaseline
+func BaselineValue(record []string) string {
+	var b bytes.Buffer
+	csvw := csv.NewWriter(&b)
+	csvw.Write(record)
+	csvw.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// baselineChange compares the raw record to the baseline, returning the change flag and
+// whether the record should be emitted
+func (w *Writer) baselineChange(record []string) (string, bool) {
+	ki := w.columnIndex(w.baseline.keyCol)
+	if ki < 0 || ki >= len(record) {
+		return ChangeAdded, true
+	}
+	prior, ok := w.baseline.prior[record[ki]]
+	if !ok {
+		return ChangeAdded, true
+	}
+	if prior != BaselineValue(record) {
+		return ChangeModified, true
+	}
+	return "", false
+}
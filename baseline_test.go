@@ -0,0 +1,17 @@
+package multiwriter
+
+import (
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	prior := map[string]string{
+		"1": BaselineValue([]string{"1", "alice"}),
+		"2": BaselineValue([]string{"2", "bob, jr"}),
+	}
+	records := [][]string{{"1", "alice"}, {"2", "bob, sr"}, {"3", "carol"}}
+	got := writeRecords(t, []string{"id", "name"}, CSVFormat, records, WithBaseline(prior, "id"))
+	if want := "id,name,change\n2,\"bob, sr\",changed\n3,carol,added\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package multiwriter
+
+import "container/list"
+
+// lruCache memoizes formatted values keyed by their input, evicting the least
+// recently used entry once it holds size entries
+type lruCache struct {
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is a cached input and its formatted output
+type lruEntry struct {
+	key   string
+	value string
+}
+
+// newLRUCache returns an empty cache holding at most size entries
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns the cached value for key and marks it as recently used
+func (c *lruCache) get(key string) (string, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// put caches value for key, evicting the least recently used entry if full
+func (c *lruCache) put(key, value string) {
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruEntry).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.size <= 0 {
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+}
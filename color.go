@@ -0,0 +1,113 @@
+package multiwriter
+
+import "os"
+
+// Color is the name of a terminal color used by Colorize and ColorFunc
+type Color string
+
+const (
+	// NoColor leaves values uncolored
+	NoColor Color = ""
+	// Black colors values black
+	Black Color = "black"
+	// Red colors values red
+	Red Color = "red"
+	// Green colors values green
+	Green Color = "green"
+	// Yellow colors values yellow
+	Yellow Color = "yellow"
+	// Blue colors values blue
+	Blue Color = "blue"
+	// Magenta colors values magenta
+	Magenta Color = "magenta"
+	// Cyan colors values cyan
+	Cyan Color = "cyan"
+	// White colors values white
+	White Color = "white"
+)
+
+// colorCodes maps color names to their SGR foreground codes
+var colorCodes = map[Color]string{
+	Black:   "30",
+	Red:     "31",
+	Green:   "32",
+	Yellow:  "33",
+	Blue:    "34",
+	Magenta: "35",
+	Cyan:    "36",
+	White:   "37",
+}
+
+// ColorMode controls whether ANSI colors are kept in the output
+type ColorMode int
+
+const (
+	// ColorAuto keeps colors only in the Table, Text and Logfmt formats when
+	// the output is a terminal and the NO_COLOR environment variable is unset
+	ColorAuto ColorMode = iota
+	// ColorAlways keeps colors in every format
+	ColorAlways
+	// ColorNever removes colors from every format
+	ColorNever
+)
+
+// Colorize returns a Formatter that colors values with color. Values are left
+// unchanged if color is unknown.
+func Colorize(color Color) Formatter {
+	return ColorFunc(func(string) Color {
+		return color
+	})
+}
+
+// ColorFunc returns a Formatter that colors each value with the color chosen
+// by fn, e.g. to color a status column by its value. Values for which fn
+// returns NoColor or an unknown color are left unchanged.
+func ColorFunc(fn func(value string) Color) Formatter {
+	return FuncFormatter(func(value string) string {
+		code, ok := colorCodes[fn(value)]
+		if !ok || value == "" {
+			return value
+		}
+		return "\x1b[" + code + "m" + value + "\x1b[0m"
+	})
+}
+
+// WithColor sets whether ANSI escape sequences, such as the colors added by
+// Colorize, are kept in formatted values. The default is ColorAuto, which
+// removes them from formats that are read by programs, such as CSV and JSON,
+// and from output that isn't a terminal.
+func WithColor(mode ColorMode) Option {
+	return func(w *Writer) {
+		w.colorMode = mode
+	}
+}
+
+// stripColors returns whether ANSI escape sequences are removed from formatted
+// values
+func (w *Writer) stripColors() bool {
+	switch w.colorMode {
+	case ColorAlways:
+		return false
+	case ColorNever:
+		return true
+	}
+	switch w.format {
+	case TableFormat, TextFormat, LogfmtFormat:
+	default:
+		return true
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return true
+	}
+	return !isTerminal(w.dest)
+}
+
+// isTerminal returns whether out is a file referring to a terminal
+func isTerminal(out interface{}) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
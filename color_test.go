@@ -0,0 +1,84 @@
+package multiwriter
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   []Option
+		want   string
+	}{
+		// output that isn't a terminal is stripped by default
+		{"csv", CSVFormat, nil, "status\nfailed\n"},
+		{"logfmt", LogfmtFormat, nil, "status=failed\n"},
+		{"csv always", CSVFormat, []Option{WithColor(ColorAlways)}, "status\n\x1b[31mfailed\x1b[0m\n"},
+		{"logfmt always", LogfmtFormat, []Option{WithColor(ColorAlways)}, "status=\"\\x1b[31mfailed\\x1b[0m\"\n"},
+		{"table never", TableFormat, []Option{WithColor(ColorNever), WithNoHeader()}, "+--------+\n| failed |\n+--------+\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithFormatter("status", Colorize(Red))}, tt.opts...)
+			if got := writeRecords(t, []string{"status"}, tt.format, [][]string{{"failed"}}, opts...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorAuto(t *testing.T) {
+	// /dev/null is a character device, like a terminal
+	tty, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer tty.Close()
+	if !isTerminal(tty) {
+		t.Skipf("%s isn't a character device", os.DevNull)
+	}
+	file, err := ioutil.TempFile("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if v, ok := os.LookupEnv("NO_COLOR"); ok {
+		os.Unsetenv("NO_COLOR")
+		defer os.Setenv("NO_COLOR", v)
+	}
+	tests := []struct {
+		name   string
+		out    *os.File
+		format string
+		want   bool
+	}{
+		{"terminal table", tty, TableFormat, false},
+		{"terminal text", tty, TextFormat, false},
+		{"terminal logfmt", tty, LogfmtFormat, false},
+		{"terminal csv", tty, CSVFormat, true},
+		{"terminal json", tty, JSONFormat, true},
+		{"file table", file, TableFormat, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.out, []string{"status"}, tt.format)
+			if w.noColor != tt.want {
+				t.Errorf("got stripping %v, want %v", w.noColor, tt.want)
+			}
+		})
+	}
+
+	os.Setenv("NO_COLOR", "")
+	defer os.Unsetenv("NO_COLOR")
+	if w := New(tty, []string{"status"}, TableFormat); !w.noColor {
+		t.Error("got colors kept with NO_COLOR set, want them stripped")
+	}
+	if w := New(tty, []string{"status"}, TableFormat, WithColor(ColorAlways)); w.noColor {
+		t.Error("got colors stripped with ColorAlways, want them kept")
+	}
+}
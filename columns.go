@@ -0,0 +1,155 @@
+package multiwriter
+
+import (
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// columnSpec is the configuration of a column added with AddColumn
+type columnSpec struct {
+	value      string
+	typ        ColumnType
+	formatters []Formatter
+}
+
+// ColumnOption configures a column added with AddColumn
+type ColumnOption func(*columnSpec)
+
+// ColumnDefault sets the value of the added column in the records written
+// before it was added
+func ColumnDefault(value string) ColumnOption {
+	return func(c *columnSpec) {
+		c.value = value
+	}
+}
+
+// ColumnOfType declares the type of data held by the added column, as
+// WithColumnTypes does
+func ColumnOfType(t ColumnType) ColumnOption {
+	return func(c *columnSpec) {
+		c.typ = t
+	}
+}
+
+// ColumnFormatter adds f to the added column's formatters, as WithFormatter
+// does
+func ColumnFormatter(f Formatter) ColumnOption {
+	return func(c *columnSpec) {
+		c.formatters = append(c.formatters, f)
+	}
+}
+
+// WithDynamicColumns allows columns to be added with AddColumn while writing,
+// e.g. for fields discovered while iterating results. Records are held in
+// memory and the header is deferred until the first Flush, after which the
+// columns are fixed.
+func WithDynamicColumns() Option {
+	return func(w *Writer) {
+		w.dynamic = true
+	}
+}
+
+// AddColumn appends a column to the writer's columns, so that the records
+// written next have one more field. Records written before are backfilled
+// with the value set by ColumnDefault, or an empty value. Columns can only be
+// added with WithDynamicColumns before the first Flush, and not while the
+// columns are locked or projected with WithColumns or WithExcludeColumns.
+// Columns appended by the writer itself, such as the row hash, stay last.
+func (w *Writer) AddColumn(name string, opts ...ColumnOption) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var spec columnSpec
+	for _, opt := range opts {
+		opt(&spec)
+	}
+	var err error
+	switch {
+	case w.closed:
+		return ErrClosed
+	case !w.dynamic:
+		err = fmt.Errorf("%w: cannot add column %s without WithDynamicColumns", ErrColumnsLocked, name)
+	case w.columnsFixed:
+		err = fmt.Errorf("%w: cannot add column %s after the header was written", ErrColumnsLocked, name)
+	case w.output != nil:
+		err = fmt.Errorf("cannot add column %s to projected columns", name)
+	case w.columnIndex(name) >= 0:
+		err = fmt.Errorf("cannot add column %s: column already exists", name)
+	}
+	if err != nil {
+		w.err = multierror.Append(w.err, err)
+		return err
+	}
+	at := len(w.columns) - w.appendedColumns()
+	previous := w.columns
+	prevType, hadType := w.types[name]
+	prevFormatters, hadFormatters := w.formatters[name]
+	if err := w.setColumns(insertValue(w.columns, at, name)); err != nil {
+		return err
+	}
+	if spec.typ != "" {
+		w.types[name] = spec.typ
+	}
+	w.formatters[name] = append(prevFormatters, spec.formatters...)
+	if err := w.backfillColumn(at, spec.value); err != nil {
+		// leave the writer as it was, without the column's type and
+		// formatters
+		w.columns = previous
+		if hadType {
+			w.types[name] = prevType
+		} else {
+			delete(w.types, name)
+		}
+		if hadFormatters {
+			w.formatters[name] = prevFormatters
+		} else {
+			delete(w.formatters, name)
+		}
+		w.err = multierror.Append(w.err, err)
+		return err
+	}
+	w.table = w.newTable()
+	return nil
+}
+
+// backfillColumn inserts value as the field at index at of the records held
+// in memory, formatted as the column's values are. The records are left
+// unchanged if the value can't be encoded.
+func (w *Writer) backfillColumn(at int, value string) error {
+	rows := make([]row, len(w.rows))
+	for i, r := range w.rows {
+		rows[i] = r
+		if r.separator {
+			continue
+		}
+		raw := insertValue(r.raw, at, value)
+		formatted, err := w.encodeValue(at, w.formatValue(at, value, raw))
+		if err != nil {
+			return err
+		}
+		rows[i].raw = raw
+		rows[i].values = insertValue(r.values, at, formatted)
+	}
+	w.rows = rows
+	return nil
+}
+
+// insertValue returns a copy of values with value inserted at index at
+func insertValue(values []string, at int, value string) []string {
+	inserted := make([]string, 0, len(values)+1)
+	inserted = append(inserted, values[:at]...)
+	inserted = append(inserted, value)
+	return append(inserted, values[at:]...)
+}
+
+// fixColumns writes the header deferred by WithDynamicColumns on the first
+// flush, after which no columns can be added
+func (w *Writer) fixColumns() {
+	if !w.dynamic || w.columnsFixed {
+		return
+	}
+	w.columnsFixed = true
+	if w.headerState == nil && len(w.columns) > 0 {
+		w.writeHeader()
+	}
+}
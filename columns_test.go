@@ -0,0 +1,38 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestAddColumnRestoredOnError(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, CSVFormat, WithDynamicColumns(),
+		WithColumnEncoding("name", charmap.ISO8859_1), WithStrictEncoding(true))
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	upper := FuncFormatter(strings.ToUpper)
+	if err := w.AddColumn("name", ColumnDefault("€"), ColumnOfType(Int), ColumnFormatter(upper)); err == nil {
+		t.Fatal("got nil error for a default that can't be encoded")
+	}
+	if _, ok := w.types["name"]; ok {
+		t.Error("got the failed column's type kept")
+	}
+	if _, ok := w.formatters["name"]; ok {
+		t.Error("got the failed column's formatters kept")
+	}
+	if err := w.AddColumn("name", ColumnDefault("n/a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"2", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, want := buf.String(), "id,name\n1,n/a\n2,bob\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
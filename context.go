@@ -0,0 +1,112 @@
+package multiwriter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// deadliner is implemented by outputs that support write deadlines, such as
+// net.Conn and os.File pipes
+type deadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// WriteContext writes the record like Write, unless ctx is already done. If
+// the output supports write deadlines, e.g. a net.Conn, the deadline of ctx
+// applies to any output written by an automatic flush and cancelling ctx
+// interrupts it, leaving the output incomplete and the Writer unusable.
+// Otherwise a write to the output that is already in progress can't be
+// interrupted.
+func (w *Writer) WriteContext(ctx context.Context, record []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var err error
+	w.withContext(ctx, func() {
+		err = w.write(record)
+	})
+	return err
+}
+
+// FlushContext flushes like Flush, unless ctx is already done, and returns the
+// error of ctx if it was cancelled or its deadline passed before the flush
+// completed. Interrupting the write to the output is subject to the same
+// conditions as WriteContext, and errors writing the output are reported by
+// Error.
+func (w *Writer) FlushContext(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if w.closed {
+		return nil
+	}
+	w.withContext(ctx, w.flush)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// the output's write deadline may expire before ctx notices its own
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// withContext runs fn with the write deadline of the output set from ctx, if
+// the output supports deadlines, and expires it if ctx is cancelled meanwhile
+func (w *Writer) withContext(ctx context.Context, fn func()) {
+	d, ok := w.dest.(deadliner)
+	if !ok || ctx.Done() == nil {
+		fn()
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		d.SetWriteDeadline(deadline)
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			d.SetWriteDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+	fn()
+	close(stop)
+	<-done
+	d.SetWriteDeadline(time.Time{})
+}
+
+// rawWriter passes bytes through to the output of a Writer
+type rawWriter struct {
+	w *Writer
+}
+
+// Write flushes any buffered records and then writes p verbatim to the output
+func (rw rawWriter) Write(p []byte) (int, error) {
+	w := rw.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	n, err := w.basew.Write(p)
+	if err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error writing raw output: %s", err))
+	}
+	return n, err
+}
+
+// RawWriter returns an io.Writer that passes bytes through to the output
+// between records, flushing buffered records before each write, so the
+// Writer can be composed with io plumbing such as io.Copy or fmt.Fprintf.
+// The bytes are not escaped, so they must be valid in the output format.
+func (w *Writer) RawWriter() io.Writer {
+	return rawWriter{w: w}
+}
@@ -0,0 +1,73 @@
+package multiwriter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestContextDone(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, CSVFormat)
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.WriteContext(ctx, []string{"2"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if err := w.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if got := buf.String(); got != "" {
+		t.Errorf("got %q flushed with a done context, want nothing", got)
+	}
+	if err := w.FlushContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id\n1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlushContextInterrupted(t *testing.T) {
+	// nothing reads from the pipe, so the flush blocks until ctx expires
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	defer conn.Close()
+	w := New(conn, []string{"id"}, CSVFormat)
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- w.FlushContext(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush wasn't interrupted")
+	}
+	if err := w.Error(); err == nil {
+		t.Error("got no error, want the interrupted write reported")
+	}
+
+	// cancelling interrupts a flush without a deadline
+	conn2, peer2 := net.Pipe()
+	defer peer2.Close()
+	defer conn2.Close()
+	w = New(conn2, []string{"id"}, CSVFormat)
+	w.Write([]string{"1"})
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := w.FlushContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
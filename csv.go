@@ -0,0 +1,270 @@
+package multiwriter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kataras/tablewriter"
+)
+
+// WithDelimiter separates CSV fields with delim instead of a comma, e.g. '\t'
+// for tab-separated values. The header uses the same delimiter.
+func WithDelimiter(delim rune) Option {
+	return func(w *Writer) {
+		w.comma = delim
+	}
+}
+
+// WithCRLF ends CSV records with \r\n instead of \n, as expected by some
+// spreadsheet imports
+func WithCRLF(crlf bool) Option {
+	return func(w *Writer) {
+		w.crlf = crlf
+	}
+}
+
+// WithCSVRecordSeparator separates CSV records with sep instead of a newline,
+// e.g. the \x1e record separator control character. Fields are quoted the
+// same way as encoding/csv does, and additionally when they contain sep.
+func WithCSVRecordSeparator(sep string) Option {
+	return func(w *Writer) {
+		w.rawRecSep = sep
+		w.csvQuoting = true
+	}
+}
+
+// WithRFC4180Strict writes CSV that follows RFC 4180 exactly: records end
+// with CRLF, fields are quoted only when they contain a quote, delimiter, CR or
+// LF, and records with fields containing NUL bytes are rejected
+func WithRFC4180Strict(strict bool) Option {
+	return func(w *Writer) {
+		w.rfc4180 = strict
+	}
+}
+
+// WithCSVEscapeMode escapes special characters in CSV fields with escapeChar
+// instead of quoting them, following the MySQL FIELDS ESCAPED BY convention:
+// the escape character, delimiter and record separator are prefixed with
+// escapeChar and NUL is written as escapeChar followed by 0.
+func WithCSVEscapeMode(escapeChar rune) Option {
+	return func(w *Writer) {
+		w.csvEscape = escapeChar
+	}
+}
+
+// WithAlignedCSV pads CSV fields with spaces after each comma so columns line
+// up visually. Records are buffered until Flush to compute column widths.
+// The output remains valid CSV: reading it with csv.Reader.TrimLeadingSpace
+// set returns the original values.
+func WithAlignedCSV(align bool) Option {
+	return func(w *Writer) {
+		w.alignCSV = align
+	}
+}
+
+// WithRawDelimiter bypasses encoding/csv for the CSV format and joins fields
+// with sep and records with recordSep, which allows multi-character
+// delimiters such as "||". By default occurrences of either separator and the
+// backslash in a value are escaped with a backslash; see WithRawEscaper.
+func WithRawDelimiter(sep, recordSep string) Option {
+	return func(w *Writer) {
+		w.rawSep = sep
+		w.rawRecSep = recordSep
+	}
+}
+
+// WithRawEscaper sets the function used to escape values written with
+// WithRawDelimiter
+func WithRawEscaper(f Formatter) Option {
+	return func(w *Writer) {
+		w.rawEscaper = f
+	}
+}
+
+// WithCSVNullString writes the CSV fields whose raw value is the one set with
+// WithNullValue as s, e.g. \N, without quoting or escaping it, so that
+// loaders can tell null fields from values
+func WithCSVNullString(s string) Option {
+	return func(w *Writer) {
+		w.csvNull = &s
+	}
+}
+
+// writeCSV writes values as a single CSV record, using the raw delimiters if set
+func (w *Writer) writeCSV(values []string) error {
+	if w.rawSep == "" {
+		return w.csvw.Write(values)
+	}
+	_, err := w.strw.WriteString(w.renderCSV(values))
+	return err
+}
+
+// writeCSVRecord writes the values of r as a single CSV record, writing the
+// fields whose raw value is the null value as the string set by
+// WithCSVNullString, unquoted and unescaped
+func (w *Writer) writeCSVRecord(r row) error {
+	if w.csvNull == nil || w.nullValue == nil {
+		return w.writeCSV(r.values)
+	}
+	isNull := func(i int) bool {
+		return i < len(r.raw) && r.raw[i] == *w.nullValue
+	}
+	if w.rawSep == "" {
+		values := append([]string(nil), r.values...)
+		for i := range values {
+			if isNull(i) {
+				values[i] = *w.csvNull
+			}
+		}
+		return w.csvw.Write(values)
+	}
+	var b strings.Builder
+	for i, v := range r.values {
+		if i > 0 {
+			b.WriteString(w.rawSep)
+		}
+		if isNull(i) {
+			b.WriteString(*w.csvNull)
+			continue
+		}
+		b.WriteString(w.rawEscaper.Format(v))
+	}
+	b.WriteString(w.rawRecSep)
+	_, err := w.strw.WriteString(b.String())
+	return err
+}
+
+// renderCSV renders values as a single CSV record, including the record
+// terminator
+func (w *Writer) renderCSV(values []string) string {
+	if w.rawSep == "" {
+		var b bytes.Buffer
+		csvw := csv.NewWriter(&b)
+		csvw.Comma = w.csvw.Comma
+		csvw.UseCRLF = w.csvw.UseCRLF
+		csvw.Write(values)
+		csvw.Flush()
+		return b.String()
+	}
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(w.rawSep)
+		}
+		b.WriteString(w.rawEscaper.Format(v))
+	}
+	b.WriteString(w.rawRecSep)
+	return b.String()
+}
+
+// writeAlignedCSV writes rows as CSV padded so that columns line up, preceded
+// by the header if it is still pending and followed by the footer, if any
+func (w *Writer) writeAlignedCSV(rows []row) {
+	var lines [][]string
+	if w.headerPending {
+		lines = append(lines, w.headerLines()...)
+		w.headerPending = false
+	}
+	for _, r := range rows {
+		lines = append(lines, r.values)
+	}
+	if footer := w.footer(); footer != nil {
+		lines = append(lines, footer)
+	}
+	var widths []int
+	for i, line := range lines {
+		cells := make([]string, len(line))
+		for j, v := range line {
+			cells[j] = w.rawEscaper.Format(v)
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := tablewriter.DisplayWidth(cells[j]); n > widths[j] {
+				widths[j] = n
+			}
+		}
+		lines[i] = cells
+	}
+	for _, cells := range lines {
+		for j, cell := range cells {
+			if j > 0 {
+				w.strw.WriteString(w.rawSep)
+				prev := tablewriter.DisplayWidth(cells[j-1])
+				w.strw.WriteString(strings.Repeat(" ", widths[j-1]-prev))
+			}
+			w.strw.WriteString(cell)
+		}
+		w.strw.WriteString(w.rawRecSep)
+	}
+}
+
+// csvRecordSep returns the default separator between CSV records
+func (w *Writer) csvRecordSep() string {
+	if w.crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// csvQuoter returns a Formatter that quotes fields following the rules of
+// encoding/csv, treating the given separators as special characters
+func csvQuoter(seps ...string) Formatter {
+	return FuncFormatter(func(field string) string {
+		if field == "" {
+			return field
+		}
+		quote := field == `\.` || strings.ContainsAny(field, "\"\r\n")
+		for _, sep := range seps {
+			quote = quote || (sep != "" && strings.Contains(field, sep))
+		}
+		if r, _ := utf8.DecodeRuneInString(field); unicode.IsSpace(r) {
+			quote = true
+		}
+		if !quote {
+			return field
+		}
+		return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+	})
+}
+
+// rfc4180Quoter returns a Formatter that quotes fields as RFC 4180 requires,
+// only when they contain a quote, CR, LF or sep
+func rfc4180Quoter(sep string) Formatter {
+	return FuncFormatter(func(field string) string {
+		if !strings.ContainsAny(field, "\"\r\n") && !strings.Contains(field, sep) {
+			return field
+		}
+		return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+	})
+}
+
+// charEscaper returns a Formatter that prefixes the escape character and each
+// of the given separators with the escape character. A NUL separator is
+// written as the escape character followed by 0.
+func charEscaper(esc string, seps ...string) Formatter {
+	oldnew := []string{esc, esc + esc}
+	for _, sep := range seps {
+		switch sep {
+		case "":
+		case "\x00":
+			oldnew = append(oldnew, sep, esc+"0")
+		default:
+			oldnew = append(oldnew, sep, esc+sep)
+		}
+	}
+	return FuncFormatter(strings.NewReplacer(oldnew...).Replace)
+}
+
+// checkNUL returns an error if any field of the record contains a NUL byte
+func checkNUL(record []string) error {
+	for i, field := range record {
+		if strings.IndexByte(field, 0) >= 0 {
+			return fmt.Errorf("field %d contains a NUL byte", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRawDelimiter(t *testing.T) {
+	records := [][]string{{"1", "a||b"}, {"2", `C:\tmp`}, {"3", "x;;y"}}
+	got := writeRecords(t, []string{"id", "name"}, CSVFormat, records, WithRawDelimiter("||", ";;"))
+	want := `id||name;;1||a\||b;;2||C:\\tmp;;3||x\;;y;;`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCSVRecordSeparator(t *testing.T) {
+	records := [][]string{{"1", "plain"}, {"2", "a\x1eb"}, {"3", "a,b"}, {"4", `say "hi"`}, {"5", ""}}
+	got := writeRecords(t, []string{"id", "name"}, CSVFormat, records, WithCSVRecordSeparator("\x1e"))
+	want := "id,name\x1e1,plain\x1e2,\"a\x1eb\"\x1e3,\"a,b\"\x1e4,\"say \"\"hi\"\"\"\x1e5,\x1e"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCSVEscapeMode(t *testing.T) {
+	records := [][]string{{"1", "a,b"}, {"2", `C:\tmp`}, {"3", "two\nlines"}, {"4", "nul\x00byte"}, {"5", `say "hi"`}}
+	got := writeRecords(t, []string{"id", "note"}, CSVFormat, records, WithCSVEscapeMode('\\'))
+	want := "id,note\n1,a\\,b\n2,C:\\\\tmp\n3,two\\\nlines\n4,nul\\0byte\n5,say \"hi\"\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// the escape character and delimiter can both be changed
+	got = writeRecords(t, []string{"a", "b"}, CSVFormat, [][]string{{"x;y", "50%"}},
+		WithDelimiter(';'), WithCSVEscapeMode('%'))
+	if want := "a;b\nx%;y;50%%\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	records := [][]string{{"1", "a;b"}, {"2", "a,b"}, {"3", "plain"}}
+	got := writeRecords(t, []string{"id", "na;me"}, CSVFormat, records, WithDelimiter(';'))
+	if want := "id;\"na;me\"\n1;\"a;b\"\n2;a,b\n3;plain\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got = writeRecords(t, []string{"id", "name"}, CSVFormat, records, WithDelimiter('\t'))
+	if want := "id\tname\n1\ta;b\n2\ta,b\n3\tplain\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRFC4180Strict(t *testing.T) {
+	records := [][]string{{"1", "plain"}, {"2", " lead"}, {"3", "a,b"}, {"4", `say "hi"`}, {"5", "two\nlines"}, {"6", ""}}
+	got := writeRecords(t, []string{"id", "note"}, CSVFormat, records, WithRFC4180Strict(true))
+	// unlike encoding/csv, leading spaces aren't quoted, and records end
+	// with CRLF
+	want := "id,note\r\n1,plain\r\n2, lead\r\n3,\"a,b\"\r\n4,\"say \"\"hi\"\"\"\r\n5,\"two\nlines\"\r\n6,\r\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "note"}, CSVFormat, WithRFC4180Strict(true))
+	err := w.Write([]string{"1", "nul\x00"})
+	if err == nil || !strings.Contains(err.Error(), "field 1 contains a NUL byte") {
+		t.Errorf("got %v, want the NUL byte rejected", err)
+	}
+	w.Close()
+	if got, want := buf.String(), "id,note\r\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTSV(t *testing.T) {
+	records := [][]string{{"1", "a,b"}, {"2", "a\tb"}, {"3", "two\nlines"}}
+	got := writeRecords(t, []string{"id", "note"}, TSVFormat, records)
+	// commas are left alone, while tabs and newlines are quoted
+	if want := "id\tnote\n1\ta,b\n2\t\"a\tb\"\n3\t\"two\nlines\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// an explicit delimiter takes precedence
+	got = writeRecords(t, []string{"id", "note"}, TSVFormat, records[:1], WithDelimiter(';'))
+	if want := "id;note\n1;a,b\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	records := [][]string{{"1", "plain"}, {"2", "two\nlines"}}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		// encoding/csv also turns newlines inside quoted fields into CRLF
+		{CSVFormat, "id,note\r\n1,plain\r\n2,\"two\r\nlines\"\r\n"},
+		{TSVFormat, "id\tnote\r\n1\tplain\r\n2\t\"two\r\nlines\"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := writeRecords(t, []string{"id", "note"}, tt.format, records, WithCRLF(true)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package multiwriter
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+)
+
+// encodeRecord transcodes formatted values of columns configured with
+// WithColumnEncoding from UTF-8 to the column's encoding
+func (w *Writer) encodeRecord(values []string) ([]string, error) {
+	if len(w.encodings) == 0 {
+		return values, nil
+	}
+	for i, v := range values {
+		encoded, err := w.encodeValue(i, v)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = encoded
+	}
+	return values, nil
+}
+
+// encodeValue transcodes v, a value of the column at index i, to the column's
+// encoding, if any
+func (w *Writer) encodeValue(i int, v string) (string, error) {
+	enc, ok := w.encodings[w.columns[i]]
+	if !ok {
+		enc, ok = w.encodings[""]
+	}
+	if !ok {
+		return v, nil
+	}
+	e := enc.NewEncoder()
+	if !w.strictEncoding {
+		e = encoding.ReplaceUnsupported(e)
+	}
+	encoded, err := e.String(v)
+	if err != nil {
+		return "", fmt.Errorf("error encoding column %s: %s", w.columns[i], err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,34 @@
+package multiwriter
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestColumnEncoding(t *testing.T) {
+	records := [][]string{{"café", "café"}, {"€5", "日本"}}
+	got := writeRecords(t, []string{"utf8", "latin1"}, CSVFormat, records,
+		WithColumnEncoding("latin1", charmap.ISO8859_1))
+	// é is a single byte in Latin-1 and unrepresentable runes are replaced
+	// with the encoding's replacement character
+	if want := "utf8,latin1\ncafé,caf\xe9\n€5,\x1a\x1a\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestColumnEncodingStrict(t *testing.T) {
+	w := New(ioutil.Discard, []string{"latin1"}, CSVFormat,
+		WithColumnEncoding("latin1", charmap.ISO8859_1), WithStrictEncoding(true))
+	if err := w.Write([]string{"café"}); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Write([]string{"€5"})
+	var re RecordError
+	if !errors.As(err, &re) || re.Index != 1 || !strings.Contains(err.Error(), "error encoding column latin1") {
+		t.Errorf("got %v, want an encoding error for record 1", err)
+	}
+}
@@ -0,0 +1,120 @@
+package multiwriter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// textLineBreaks are the characters that would start a new line in the Text
+// format and corrupt the block the value belongs to
+const textLineBreaks = "\r\n\v\f\u0085\u2028\u2029"
+
+// sgrEscape matches an ANSI color sequence, which tables measure correctly
+var sgrEscape = regexp.MustCompile(`^\x1b\[[0-9;]*m$`)
+
+// WithRawValues writes values as they are formatted, without the
+// sanitization the Text and Table formats apply by default: Text values
+// containing line breaks are quoted as Go string literals, and Table cells
+// have control characters and ANSI escape sequences other than colors
+// removed and tabs replaced by spaces so columns stay aligned.
+func WithRawValues(raw bool) Option {
+	return func(w *Writer) {
+		w.rawValues = raw
+	}
+}
+
+// EscapeFormatter returns a Formatter that escapes backslashes and control
+// characters, including newlines and tabs, as Go escape sequences, e.g. for a
+// free-text column that must stay on one line in any format
+func EscapeFormatter() Formatter {
+	return FuncFormatter(func(value string) string {
+		if strings.IndexFunc(value, func(r rune) bool {
+			return r == '\\' || unicode.IsControl(r)
+		}) < 0 {
+			return value
+		}
+		var b strings.Builder
+		for _, r := range value {
+			switch {
+			case r == '\\':
+				b.WriteString(`\\`)
+			case unicode.IsControl(r):
+				q := strconv.QuoteRune(r)
+				b.WriteString(q[1 : len(q)-1])
+			default:
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	})
+}
+
+// quoteTextValue returns whether the Text format value must be quoted
+func (w *Writer) quoteTextValue(v string) bool {
+	if w.quoteText && strings.ContainsAny(v, ":\r\n\"") {
+		return true
+	}
+	return !w.rawValues && strings.ContainsAny(v, textLineBreaks)
+}
+
+// tableValues returns the values with each cell sanitized for the Table
+// format, unless WithRawValues is set
+func (w *Writer) tableValues(values []string) []string {
+	if w.rawValues {
+		return values
+	}
+	var cells []string
+	for i, v := range values {
+		if strings.IndexFunc(v, isTableControl) < 0 {
+			continue
+		}
+		if cells == nil {
+			cells = append([]string(nil), values...)
+		}
+		cells[i] = sanitizeCell(v)
+	}
+	if cells == nil {
+		return values
+	}
+	return cells
+}
+
+// sanitizeCell removes control characters and ANSI escape sequences other
+// than colors from v and replaces tabs with spaces, keeping newlines
+func sanitizeCell(v string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range ansiEscape.FindAllStringIndex(v, -1) {
+		b.WriteString(stripControl(v[last:m[0]]))
+		if seq := v[m[0]:m[1]]; sgrEscape.MatchString(seq) {
+			b.WriteString(seq)
+		}
+		last = m[1]
+	}
+	b.WriteString(stripControl(v[last:]))
+	return b.String()
+}
+
+// stripControl removes control characters other than newlines from v and
+// replaces tabs with spaces
+func stripControl(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == '\n':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, v)
+}
+
+// isTableControl returns whether r is a control character that breaks the
+// alignment of a table cell
+func isTableControl(r rune) bool {
+	return r != '\n' && unicode.IsControl(r)
+}
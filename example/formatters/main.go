@@ -19,7 +19,7 @@ var records = [][]string{
 }
 
 func main() {
-	nameFormatter := multiwriter.BasicFormatter{"** %s **"}
+	nameFormatter := multiwriter.BasicFormatter{FmtString: "** %s **"}
 	departmentFormatter := multiwriter.FuncFormatter(func(str string) string {
 		return strings.ToUpper(str)
 	})
@@ -40,16 +40,10 @@ func main() {
 		multiwriter.WithFormatter(columns[1], departmentFormatter),
 		multiwriter.WithFormatter(columns[2], sizeFormatter),
 	)
-	defer func(w *multiwriter.Writer) {
-		w.Flush()
-		if err := w.Error(); err != nil {
-			log.Fatal(err)
-		}
-	}(writer)
-
-	for i, record := range records {
-		if err := writer.Write(record); err != nil {
-			log.Printf("could not write record %d", i)
-		}
+	if err := writer.WriteAll(records); err != nil {
+		log.Printf("could not write records: %s", err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,82 @@
+package multiwriter
+
+import "time"
+
+// WithSize modifies the size of the internal buffer. Once roughly this many
+// bytes of records have been written, the writer flushes them automatically.
+// A Table is only rendered whole so its columns line up, and is held until
+// Flush unless WithMaxBufferedRows, WithMaxBufferedBytes or WithStreaming is
+// set.
+func WithSize(size int) Option {
+	return func(w *Writer) {
+		w.size = size
+	}
+}
+
+// WithMaxBufferedRows flushes the writer automatically once rows records have
+// been written since the last flush, in every format. Records held in memory
+// by options such as WithStableOutput or WithSort are flushed as a batch, so
+// those options apply to each batch separately, and a Table is rendered as a
+// separate table for each batch. Columnar JSON and XLSX are still only
+// written on Close.
+func WithMaxBufferedRows(rows int) Option {
+	return func(w *Writer) {
+		w.maxRows = rows
+	}
+}
+
+// WithMaxBufferedBytes flushes the writer automatically once roughly bytes of
+// formatted values have been written since the last flush, in every format,
+// batching records held in memory as WithMaxBufferedRows does
+func WithMaxBufferedBytes(bytes int) Option {
+	return func(w *Writer) {
+		w.maxBytes = bytes
+	}
+}
+
+// WithFlushRateLimit makes automatic flushes, such as those triggered by
+// WithSize, WithMaxBufferedRows or WithMaxBufferedBytes, happen no more often
+// than every min, coalescing frequent small flushes when streaming to a slow
+// sink. Records keep being buffered in the meantime. Flush and WriteFlush
+// still flush immediately.
+func WithFlushRateLimit(min time.Duration) Option {
+	return func(w *Writer) {
+		w.flushRate = min
+	}
+}
+
+// flushDue returns whether an automatic flush may happen now, given the rate
+// limit set by WithFlushRateLimit
+func (w *Writer) flushDue() bool {
+	return w.flushRate <= 0 || time.Since(w.lastFlush) >= w.flushRate
+}
+
+// countPending adds values to the rows and bytes written since the last flush
+func (w *Writer) countPending(values []string) {
+	w.pendingRows++
+	for _, v := range values {
+		w.pending += len(v) + 1
+	}
+}
+
+// bufferFull returns whether the rows or bytes written since the last flush
+// reached the limits set by WithMaxBufferedRows or WithMaxBufferedBytes
+func (w *Writer) bufferFull() bool {
+	return (w.maxRows > 0 && w.pendingRows >= w.maxRows) ||
+		(w.maxBytes > 0 && w.pending >= w.maxBytes)
+}
+
+// autoFlush writes records held by the format writer to the output without
+// ending the current output, so the footer is left for the next Flush and a
+// Table continues under the header it already rendered
+func (w *Writer) autoFlush() {
+	w.pending = 0
+	w.pendingRows = 0
+	w.lastFlush = time.Now()
+	if w.format == TableFormat && !w.transpose {
+		w.renderTable(false)
+	} else {
+		w.flushBuffer()
+	}
+	w.flushOutput()
+}
@@ -0,0 +1,152 @@
+package multiwriter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// NumberFormatter formats numbers with the digit grouping and decimal
+// separator of Locale, e.g. "1.234.567,89" for language.German. Values are
+// rounded to Decimals fractional digits, or if Decimals is zero to at most
+// three as is the locale default. Values that are not numbers are passed
+// through unchanged.
+type NumberFormatter struct {
+	Locale   language.Tag
+	Decimals int
+}
+
+// Format formats value as a number in Locale
+func (nf NumberFormatter) Format(value string) string {
+	f, ok := parseFinite(value)
+	if !ok {
+		return value
+	}
+	return message.NewPrinter(nf.Locale).Sprint(number.Decimal(f, decimals(nf.Decimals)...))
+}
+
+// PercentFormatter formats fractions as percentages in Locale, e.g. 0.25 as
+// "25%", with Decimals fractional digits. Values that are not numbers are
+// passed through unchanged.
+type PercentFormatter struct {
+	Locale   language.Tag
+	Decimals int
+}
+
+// Format formats value as a percentage in Locale
+func (pf PercentFormatter) Format(value string) string {
+	f, ok := parseFinite(value)
+	if !ok {
+		return value
+	}
+	return message.NewPrinter(pf.Locale).Sprint(number.Percent(f, decimals(pf.Decimals)...))
+}
+
+// CurrencyFormatter formats amounts of Currency, e.g. currency.EUR, with the
+// symbol used in Locale followed by the amount in Locale, rounded to the
+// currency's standard number of fractional digits. Values that are not
+// numbers are passed through unchanged.
+type CurrencyFormatter struct {
+	Locale   language.Tag
+	Currency currency.Unit
+}
+
+// Format formats value as an amount of Currency in Locale
+func (cf CurrencyFormatter) Format(value string) string {
+	f, ok := parseFinite(value)
+	if !ok {
+		return value
+	}
+	p := message.NewPrinter(cf.Locale)
+	scale, _ := currency.Standard.Rounding(cf.Currency)
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	amount := number.Decimal(f, number.MinFractionDigits(scale), number.MaxFractionDigits(scale))
+	return sign + p.Sprint(currency.Symbol(cf.Currency)) + p.Sprint(amount)
+}
+
+// ByteSizeFormatter formats byte counts for humans, e.g. 1536 as "1.5 kB", or
+// as "1.5 KiB" with Binary set, with at most one fractional digit in Locale.
+// Values that are not numbers are passed through unchanged.
+type ByteSizeFormatter struct {
+	Locale language.Tag
+	Binary bool
+}
+
+// Format formats value as a byte size
+func (bf ByteSizeFormatter) Format(value string) string {
+	f, ok := parseFinite(value)
+	if !ok {
+		return value
+	}
+	base, units := 1000.0, []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	if bf.Binary {
+		base, units = 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	}
+	unit := 0
+	for math.Abs(f) >= base && unit < len(units)-1 {
+		f /= base
+		unit++
+	}
+	digits := 1
+	if unit == 0 {
+		digits = 0
+	}
+	n := number.Decimal(f, number.MaxFractionDigits(digits))
+	return message.NewPrinter(bf.Locale).Sprint(n) + " " + units[unit]
+}
+
+// DateFormatter reformats timestamps parsed with the layout From, or
+// time.RFC3339 if empty, using the layout To, or time.RFC3339 if empty.
+// Timestamps are converted to Location first if it is set. Values that don't
+// parse are passed through unchanged.
+type DateFormatter struct {
+	From     string
+	To       string
+	Location *time.Location
+}
+
+// Format reformats value from layout From to layout To
+func (df DateFormatter) Format(value string) string {
+	from, to := df.From, df.To
+	if from == "" {
+		from = time.RFC3339
+	}
+	if to == "" {
+		to = time.RFC3339
+	}
+	t, err := time.Parse(from, strings.TrimSpace(value))
+	if err != nil {
+		return value
+	}
+	if df.Location != nil {
+		t = t.In(df.Location)
+	}
+	return t.Format(to)
+}
+
+// parseFinite parses value as a finite float
+func parseFinite(value string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// decimals returns the number options fixing the number of fractional digits
+// to n, or none if n is zero
+func decimals(n int) []number.Option {
+	if n <= 0 {
+		return nil
+	}
+	return []number.Option{number.MinFractionDigits(n), number.MaxFractionDigits(n)}
+}
@@ -0,0 +1,48 @@
+package multiwriter
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+func TestLocaleFormatters(t *testing.T) {
+	tests := []struct {
+		name  string
+		f     Formatter
+		value string
+		want  string
+	}{
+		{"number", NumberFormatter{Locale: language.German}, "1234567.891", "1.234.567,891"},
+		{"number decimals", NumberFormatter{Locale: language.English, Decimals: 2}, "1234567.891", "1,234,567.89"},
+		{"number padded", NumberFormatter{Locale: language.English, Decimals: 2}, "-1536", "-1,536.00"},
+		{"number not a number", NumberFormatter{Locale: language.German}, "n/a", "n/a"},
+		{"number infinite", NumberFormatter{Locale: language.German}, "Inf", "Inf"},
+		{"percent", PercentFormatter{Locale: language.English}, "0.25", "25%"},
+		{"percent decimals", PercentFormatter{Locale: language.French, Decimals: 1}, "0.25", "25,0\u00a0%"},
+		{"percent NaN", PercentFormatter{Locale: language.English}, "NaN", "NaN"},
+		{"currency", CurrencyFormatter{Locale: language.English, Currency: currency.USD}, "1234567.891", "$1,234,567.89"},
+		{"currency negative", CurrencyFormatter{Locale: language.English, Currency: currency.USD}, "-1536", "-$1,536.00"},
+		{"currency locale", CurrencyFormatter{Locale: language.German, Currency: currency.EUR}, "0.25", "€0,25"},
+		{"currency rounding", CurrencyFormatter{Locale: language.English, Currency: currency.JPY}, "1234567.891", "¥1,234,568"},
+		{"currency not a number", CurrencyFormatter{Locale: language.English, Currency: currency.USD}, "free", "free"},
+		{"bytes", ByteSizeFormatter{Locale: language.English}, "999", "999 B"},
+		{"bytes kilo", ByteSizeFormatter{Locale: language.English}, "1536", "1.5 kB"},
+		{"bytes mega", ByteSizeFormatter{Locale: language.English}, "1234567", "1.2 MB"},
+		{"bytes binary", ByteSizeFormatter{Locale: language.German, Binary: true}, "-1536", "-1,5 KiB"},
+		{"bytes not a number", ByteSizeFormatter{Locale: language.English}, "n/a", "n/a"},
+		{"date", DateFormatter{To: "2006-01-02"}, "2024-03-01T12:30:00Z", "2024-03-01"},
+		{"date from", DateFormatter{From: "2006-01-02 15:04", To: time.Kitchen}, "2024-03-01 12:30", "12:30PM"},
+		{"date location", DateFormatter{Location: time.FixedZone("EST", -5*60*60)}, "2024-03-01T12:30:00Z", "2024-03-01T07:30:00-05:00"},
+		{"date unparsed", DateFormatter{To: "2006-01-02"}, "2024-03-01 12:30", "2024-03-01 12:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Format(tt.value); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ module go.enc.dev/multiwriter
 go 1.15
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/kataras/tablewriter v0.0.0-20180708051242-e063d29b7c23
-	github.com/mattn/go-runewidth v0.0.9 // indirect
+	github.com/mattn/go-runewidth v0.0.9
+	golang.org/x/text v0.3.6
 )
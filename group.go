@@ -0,0 +1,152 @@
+package multiwriter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// grouping describes how records are grouped into sections
+type grouping struct {
+	column string
+	counts bool
+	totals []groupTotal
+}
+
+// groupTotal is a column aggregated in each section header
+type groupTotal struct {
+	column string
+	agg    AggFunc
+}
+
+// WithGroupBy buffers records until Flush and emits them grouped by the value
+// of column, in the order each value was first written. The Table and Text
+// formats render a section header before each group, which can be extended
+// with WithGroupCounts and WithGroupTotal. In a Table the section header is a
+// row holding the group's value and totals in their columns, so the groups
+// share the column widths. Sections are not rendered with WithSubtotals,
+// which groups the Table by itself.
+func WithGroupBy(column string) Option {
+	return func(w *Writer) {
+		if w.groupBy == nil {
+			w.groupBy = &grouping{}
+		}
+		w.groupBy.column = column
+	}
+}
+
+// WithGroupCounts adds the number of records of each group to its section
+// header set by WithGroupBy
+func WithGroupCounts() Option {
+	return func(w *Writer) {
+		if w.groupBy == nil {
+			w.groupBy = &grouping{}
+		}
+		w.groupBy.counts = true
+	}
+}
+
+// WithGroupTotal adds the numeric values of column aggregated with agg over
+// each group to its section header set by WithGroupBy
+func WithGroupTotal(column string, agg AggFunc) Option {
+	return func(w *Writer) {
+		if w.groupBy == nil {
+			w.groupBy = &grouping{}
+		}
+		w.groupBy.totals = append(w.groupBy.totals, groupTotal{column: column, agg: agg})
+	}
+}
+
+// grouping returns whether records are grouped with WithGroupBy
+func (w *Writer) grouping() bool {
+	return w.groupBy != nil && w.groupBy.column != ""
+}
+
+// groupRows stably sorts rows by the order in which the raw value of the group
+// column first appears and, for the Table and Text formats, inserts a section
+// row before each group, set apart from the previous group by a blank row in
+// a Table. Separator rows are dropped.
+func (w *Writer) groupRows(rows []row) []row {
+	gi := w.columnIndex(w.groupBy.column)
+	if gi < 0 {
+		return rows
+	}
+	var keys []string
+	groups := map[string][]row{}
+	for _, r := range rows {
+		if r.separator {
+			continue
+		}
+		key := r.raw[gi]
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	sections := w.subtotals == nil && ((w.format == TableFormat && !w.transpose) || w.format == TextFormat)
+	out := rows[:0:0]
+	for i, key := range keys {
+		group := groups[key]
+		if sections {
+			if i > 0 && w.format == TableFormat {
+				out = append(out, row{separator: true})
+			}
+			out = append(out, row{section: true, values: w.sectionValues(gi, group)})
+		}
+		out = append(out, group...)
+	}
+	return out
+}
+
+// sectionValues returns the section header of a group of rows whose group
+// column, at index gi, has the same raw value. For a Table these are the
+// cells of the section row, and for Text the section's title.
+func (w *Writer) sectionValues(gi int, group []row) []string {
+	names := w.headerNames()
+	cells := make([]string, len(w.columns))
+	cells[gi] = group[0].values[gi]
+	var details []string
+	if w.groupBy.counts {
+		noun := "records"
+		if len(group) == 1 {
+			noun = "record"
+		}
+		details = append(details, fmt.Sprintf("%d %s", len(group), noun))
+		cells[gi] += " (" + details[0] + ")"
+	}
+	for _, total := range w.groupBy.totals {
+		i := w.columnIndex(total.column)
+		if i < 0 {
+			continue
+		}
+		values := make([]string, len(group))
+		for j, r := range group {
+			values[j] = r.raw[i]
+		}
+		cells[i] = formatNumber(total.agg(parseNumbers(values)))
+		details = append(details, names[i]+": "+cells[i])
+	}
+	if w.format == TableFormat {
+		return cells
+	}
+	title := names[gi] + ": " + group[0].values[gi]
+	if len(details) > 0 {
+		title += " (" + strings.Join(details, ", ") + ")"
+	}
+	return []string{title}
+}
+
+// writeSection writes a section header set by WithGroupBy, as a Table row or
+// as a title line in Text
+func (w *Writer) writeSection(values []string) error {
+	switch w.format {
+	case TableFormat:
+		if w.stream != nil {
+			return w.writeStreamRow(values, true)
+		}
+		w.table.Append(values)
+	case TextFormat:
+		_, err := w.strw.WriteString("== " + values[0] + " ==\n")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package multiwriter
+
+import "testing"
+
+func TestGroupBy(t *testing.T) {
+	records := [][]string{{"b", "x", "1"}, {"a", "y", "2"}, {"b", "z", "n/a"}, {"a", "w", "4"}}
+	summary := []Option{WithGroupBy("team"), WithGroupCounts(), WithGroupTotal("n", Sum)}
+	tests := []struct {
+		name   string
+		format string
+		opts   []Option
+		want   string
+	}{
+		// groups are in the order their value was first written
+		{"csv", CSVFormat, summary, "team,name,n\nb,x,1\nb,z,n/a\na,y,2\na,w,4\n"},
+		{"table", TableFormat, []Option{WithGroupBy("team")}, `+------+------+-----+
+| TEAM | NAME |  N  |
++------+------+-----+
+| b    |      |     |
+| b    | x    |   1 |
+| b    | z    | n/a |
+|      |      |     |
+| a    |      |     |
+| a    | y    |   2 |
+| a    | w    |   4 |
++------+------+-----+
+`},
+		{"table summary", TableFormat, summary, `+---------------+------+-----+
+|     TEAM      | NAME |  N  |
++---------------+------+-----+
+| b (2 records) |      |   1 |
+| b             | x    |   1 |
+| b             | z    | n/a |
+|               |      |     |
+| a (2 records) |      |   6 |
+| a             | y    |   2 |
+| a             | w    |   4 |
++---------------+------+-----+
+`},
+		{"text summary", TextFormat, summary, "== team: b (2 records, n: 1) ==\n---\nteam: b\nname: x\nn: 1\n---\nteam: b\nname: z\nn: n/a\n" +
+			"== team: a (2 records, n: 6) ==\n---\nteam: a\nname: y\nn: 2\n---\nteam: a\nname: w\nn: 4\n"},
+		{"unknown column", CSVFormat, []Option{WithGroupBy("missing")}, "team,name,n\nb,x,1\na,y,2\nb,z,n/a\na,w,4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeRecords(t, []string{"team", "name", "n"}, tt.format, records, tt.opts...); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
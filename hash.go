@@ -0,0 +1,26 @@
+package multiwriter
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+)
+
+// rowHash computes the hash column added by WithRowHashColumn
+type rowHash struct {
+	algo func() hash.Hash
+}
+
+// sum returns the hex encoded hash of the raw record. Every field is prefixed
+// with its length so that records whose fields concatenate to the same string
+// still hash differently.
+func (rh *rowHash) sum(record []string) string {
+	h := rh.algo()
+	var n [8]byte
+	for _, field := range record {
+		binary.BigEndian.PutUint64(n[:], uint64(len(field)))
+		h.Write(n[:])
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package multiwriter
+
+import "sync"
+
+// HeaderState coordinates header emission between writers that shard one
+// logical output, e.g. parallel workers writing to one merged file, so that
+// only the first writer to flush emits the header. It is safe for concurrent
+// use.
+type HeaderState struct {
+	mu      sync.Mutex
+	written bool
+}
+
+// NewHeaderState returns a HeaderState for which no header has been written
+func NewHeaderState() *HeaderState {
+	return &HeaderState{}
+}
+
+// claim returns true for the first caller only
+func (s *HeaderState) claim() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.written {
+		return false
+	}
+	s.written = true
+	return true
+}
+
+// decideSharedHeader writes the header if this writer is the first of those
+// sharing its header state to flush
+func (w *Writer) decideSharedHeader() {
+	if w.headerState == nil || w.headerDecided {
+		return
+	}
+	w.headerDecided = true
+	if w.headerState.claim() {
+		w.writeHeader()
+	}
+}
@@ -0,0 +1,73 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.buf.Write(p)
+}
+
+func TestSharedHeaderState(t *testing.T) {
+	// the first writer to flush emits the header, whichever was created
+	// first
+	var a, b bytes.Buffer
+	state := NewHeaderState()
+	wa := New(&a, []string{"id"}, CSVFormat, WithSharedHeaderState(state))
+	wb := New(&b, []string{"id"}, CSVFormat, WithSharedHeaderState(state))
+	wa.Write([]string{"1"})
+	wb.Write([]string{"2"})
+	wb.Flush()
+	wa.Flush()
+	wa.Close()
+	wb.Close()
+	if got, want := a.String()+"|"+b.String(), "1\n|id\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSharedHeaderStateConcurrent(t *testing.T) {
+	const shards = 8
+	var out lockedBuffer
+	state := NewHeaderState()
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := New(&out, []string{"id", "name"}, CSVFormat, WithSharedHeaderState(state))
+			for j := 0; j < 10; j++ {
+				w.Write([]string{strconv.Itoa(i*10 + j), "x"})
+			}
+			if err := w.Close(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSuffix(out.buf.String(), "\n"), "\n")
+	headers := 0
+	for _, line := range lines {
+		if line == "id,name" {
+			headers++
+		}
+	}
+	if headers != 1 {
+		t.Errorf("got %d headers across %d writers, want 1", headers, shards)
+	}
+	if got, want := len(lines), shards*10+1; got != want {
+		t.Errorf("got %d lines, want %d", got, want)
+	}
+}
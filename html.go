@@ -0,0 +1,117 @@
+package multiwriter
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// HTMLCell is a cell of a row rendered by the HTML format
+type HTMLCell struct {
+	Column string
+	Value  string
+}
+
+// HTMLRow is the data passed to the template set with WithHTMLTemplate
+type HTMLRow struct {
+	// Index is the index of the record among those written
+	Index int
+	Cells []HTMLCell
+}
+
+// writeHTML writes the row as a row of the HTML table, opening the table
+// first if needed
+func (w *Writer) writeHTML(r row) error {
+	if !w.htmlOpen {
+		w.renderHTMLOpen(&w.str)
+		w.str.WriteString("<tbody>\n")
+		w.htmlOpen = true
+	}
+	if err := w.renderHTMLRow(&w.str, r.index, r.values); err != nil {
+		w.str.Reset()
+		return err
+	}
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderHTMLHeader renders the opening of the HTML table with its header into
+// b
+func (w *Writer) renderHTMLHeader(b *strings.Builder) {
+	w.renderHTMLOpen(b)
+	b.WriteString("<thead>\n<tr>")
+	th := "<th>"
+	if w.htmlScope {
+		th = `<th scope="col">`
+	}
+	for _, col := range w.headerNames() {
+		b.WriteString(th + html.EscapeString(col) + "</th>")
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+}
+
+// renderHTMLOpen renders the opening tag of the HTML table into b, followed by
+// the caption set with WithHTMLCaption if any
+func (w *Writer) renderHTMLOpen(b *strings.Builder) {
+	b.WriteString("<table>\n")
+	if w.htmlCaption != "" {
+		b.WriteString("<caption>" + html.EscapeString(w.htmlCaption) + "</caption>\n")
+	}
+}
+
+// renderHTMLRow renders values into b as a table row, using the template set
+// with WithHTMLTemplate if any
+func (w *Writer) renderHTMLRow(b *strings.Builder, index int, values []string) error {
+	if w.htmlTemplate != nil {
+		data := HTMLRow{Index: index, Cells: make([]HTMLCell, len(values))}
+		for i, v := range values {
+			data.Cells[i] = HTMLCell{Column: w.columns[i], Value: v}
+		}
+		return w.htmlTemplate.Execute(b, data)
+	}
+	b.WriteString("<tr>")
+	for _, v := range values {
+		b.WriteString("<td>" + html.EscapeString(v) + "</td>")
+	}
+	b.WriteString("</tr>\n")
+	return nil
+}
+
+// closeHTML closes the HTML table if one is open
+func (w *Writer) closeHTML() error {
+	if !w.htmlOpen {
+		return nil
+	}
+	w.htmlOpen = false
+	if _, err := w.strw.WriteString("</tbody>\n</table>\n"); err != nil {
+		return err
+	}
+	return w.strw.Flush()
+}
+
+// WithHTMLTemplate renders each row of the HTML format with t, which is
+// executed with an HTMLRow and should produce a complete <tr> element, e.g.
+// <tr>{{range .Cells}}<td>{{.Value}}</td>{{end}}</tr>. Values are escaped by
+// html/template.
+func WithHTMLTemplate(t *template.Template) Option {
+	return func(w *Writer) {
+		w.htmlTemplate = t
+	}
+}
+
+// WithHTMLCaption adds a <caption> holding text to the table of the HTML
+// format, describing the table for screen readers and published reports
+func WithHTMLCaption(text string) Option {
+	return func(w *Writer) {
+		w.htmlCaption = text
+	}
+}
+
+// WithHTMLScope sets scope="col" on the header cells of the HTML format, so
+// assistive technologies associate each cell with its column header
+func WithHTMLScope(scope bool) Option {
+	return func(w *Writer) {
+		w.htmlScope = scope
+	}
+}
@@ -0,0 +1,67 @@
+package multiwriter
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+)
+
+func TestHTMLCaptionAndScope(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, HTMLFormat, WithHTMLCaption("Users & groups"), WithHTMLScope(true))
+	if err := w.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "<table>\n<caption>Users &amp; groups</caption>\n<thead>\n" +
+		"<tr><th scope=\"col\">id</th><th scope=\"col\">name</th></tr>\n</thead>\n<tbody>\n" +
+		"<tr><td>1</td><td>alice</td></tr>\n</tbody>\n</table>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTMLDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, HTMLFormat)
+	w.Write([]string{"1"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "<table>\n<thead>\n<tr><th>id</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td></tr>\n</tbody>\n</table>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTMLEscaping(t *testing.T) {
+	got := writeRecords(t, []string{"<id>", "name"}, HTMLFormat, [][]string{{"1", `<script>alert("x&y")</script>`}})
+	want := "<table>\n<thead>\n<tr><th>&lt;id&gt;</th><th>name</th></tr>\n</thead>\n<tbody>\n" +
+		"<tr><td>1</td><td>&lt;script&gt;alert(&#34;x&amp;y&#34;)&lt;/script&gt;</td></tr>\n</tbody>\n</table>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTMLTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("row").Parse(
+		`<tr id="r{{.Index}}">{{range .Cells}}<td class="{{.Column}}">{{.Value}}</td>{{end}}</tr>` + "\n"))
+	got := writeRecords(t, []string{"id", "name"}, HTMLFormat, [][]string{{"1", "alice"}, {"2", "<b>bob</b>"}},
+		WithHTMLTemplate(tmpl), WithNoHeader())
+	want := "<table>\n<tbody>\n" +
+		"<tr id=\"r0\"><td class=\"id\">1</td><td class=\"name\">alice</td></tr>\n" +
+		"<tr id=\"r1\"><td class=\"id\">2</td><td class=\"name\">&lt;b&gt;bob&lt;/b&gt;</td></tr>\n" +
+		"</tbody>\n</table>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	bad := template.Must(template.New("row").Parse(`{{.Missing}}`))
+	w := New(&buf, []string{"id"}, HTMLFormat, WithHTMLTemplate(bad))
+	if err := w.Write([]string{"1"}); err == nil {
+		t.Error("got nil error from a failing template")
+	}
+}
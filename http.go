@@ -0,0 +1,90 @@
+package multiwriter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// contentTypes maps formats to the media type of their output
+var contentTypes = map[string]string{
+	CSVFormat:      "text/csv; charset=utf-8",
+	TSVFormat:      "text/tab-separated-values; charset=utf-8",
+	JSONFormat:     "application/json",
+	NDJSONFormat:   "application/x-ndjson",
+	YAMLFormat:     "application/yaml",
+	HTMLFormat:     "text/html; charset=utf-8",
+	MarkdownFormat: "text/markdown; charset=utf-8",
+	PromFormat:     "text/plain; version=0.0.4; charset=utf-8",
+	XLSXFormat:     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ContentType returns the media type of the output of format, for the
+// Content-Type header of an HTTP response. Formats without a specific media
+// type, such as Table and Text, are plain text.
+func ContentType(format string) string {
+	if t, ok := contentTypes[format]; ok {
+		return t
+	}
+	return "text/plain; charset=utf-8"
+}
+
+const (
+	// RecordsTrailer is the HTTP trailer holding the number of records
+	// written to a response by a Writer from NewHTTPSink
+	RecordsTrailer = "X-Records"
+	// ErrorTrailer is the HTTP trailer holding the error of a Writer from
+	// NewHTTPSink, if any
+	ErrorTrailer = "X-Error"
+)
+
+// NewHTTPSink returns a new Writer that streams its output to an HTTP
+// response, along with a func that closes the writer and sets the response's
+// trailers. The Content-Type header is set for the format unless it is
+// already set, and every flush sends what was written so far, as a chunk when
+// the response uses chunked transfer encoding. Once closed, RecordsTrailer
+// holds the number of records written and ErrorTrailer the writer's error.
+func NewHTTPSink(rw http.ResponseWriter, columns []string, format string, opts ...Option) (*Writer, func() error) {
+	if rw.Header().Get("Content-Type") == "" {
+		rw.Header().Set("Content-Type", ContentType(format))
+	}
+	rw.Header().Add("Trailer", RecordsTrailer)
+	rw.Header().Add("Trailer", ErrorTrailer)
+	flusher, _ := rw.(http.Flusher)
+	w := NewFunc(func(rendered []byte) error {
+		if _, err := rw.Write(rendered); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}, columns, format, opts...)
+	closeFn := func() error {
+		err := w.Close()
+		w.mu.Lock()
+		records := w.written()
+		w.mu.Unlock()
+		rw.Header().Set(RecordsTrailer, strconv.Itoa(records))
+		if err != nil {
+			rw.Header().Set(ErrorTrailer, trailerError(err))
+		}
+		return err
+	}
+	return w, closeFn
+}
+
+// trailerError renders err on a single line, joining aggregated errors
+func trailerError(err error) string {
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		return err.Error()
+	}
+	msgs := make([]string, len(merr.Errors))
+	for i, e := range merr.Errors {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,99 @@
+package multiwriter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "text/csv; charset=utf-8"},
+		{NDJSONFormat, "application/x-ndjson"},
+		{XLSXFormat, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{TableFormat, "text/plain; charset=utf-8"},
+		{"unknown", "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		if got := ContentType(tt.format); got != tt.want {
+			t.Errorf("got %q for %s, want %q", got, tt.format, tt.want)
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w, closeFn := NewHTTPSink(rw, []string{"id", "name"}, CSVFormat)
+		w.Write([]string{"1", "alice"})
+		w.Flush()
+		w.Write([]string{"2", "bob"})
+		if r.URL.Query().Get("fail") != "" {
+			w.Write([]string{"3"})
+		}
+		closeFn()
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		query   string
+		records string
+		err     string
+	}{
+		{"ok", "", "2", ""},
+		{"error", "?fail=1", "2", "record 2: record length mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(body), "id,name\n1,alice\n2,bob\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Content-Type"), "text/csv; charset=utf-8"; got != want {
+				t.Errorf("got content type %q, want %q", got, want)
+			}
+			// the response was streamed, so its length wasn't known up front
+			if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+				t.Errorf("got transfer encoding %v, want chunked", resp.TransferEncoding)
+			}
+			if got := resp.Trailer.Get(RecordsTrailer); got != tt.records {
+				t.Errorf("got %s records, want %s", got, tt.records)
+			}
+			if got := resp.Trailer.Get(ErrorTrailer); tt.err == "" && got != "" || !strings.Contains(got, tt.err) {
+				t.Errorf("got error trailer %q, want %q", got, tt.err)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkContentTypeSet(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/plain")
+	w, closeFn := NewHTTPSink(rec, []string{"id"}, JSONFormat)
+	w.Write([]string{"1"})
+	if err := closeFn(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("got content type %q, want the one already set", got)
+	}
+	if !rec.Flushed {
+		t.Error("got the response unflushed, want it flushed")
+	}
+	if got, want := rec.Body.String(), "[\n{\"id\":\"1\"}\n]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package multiwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jsonNumber matches values that are valid JSON numbers
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// jsonString encodes s as a JSON string without escaping HTML characters
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// jsonValue encodes the column's value as a JSON number if it looks like one
+// and WithJSONNumbers is set or the column is declared as Int or Float, as a
+// JSON boolean if the column is declared as Bool, and as a string otherwise
+func (w *Writer) jsonValue(column, v string) string {
+	switch {
+	case (w.jsonNumbers || w.numericColumn(column)) && jsonNumber.MatchString(v):
+		return v
+	case w.types[column] == Bool && (v == "true" || v == "false"):
+		return v
+	}
+	return jsonString(v)
+}
+
+// renderJSON renders values into b as a JSON object keyed by column name, with
+// keys in column order
+func (w *Writer) renderJSON(b *strings.Builder, values []string) {
+	b.WriteString("{")
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(jsonString(w.columns[i]))
+		b.WriteString(":")
+		b.WriteString(w.jsonValue(w.columns[i], v))
+	}
+	b.WriteString("}")
+}
+
+// writeJSON writes the row as an element of the streamed JSON array, or as a
+// line of its own with WithJSONLines. With WithChunkSize, a full array is
+// terminated and the row starts the next one.
+func (w *Writer) writeJSON(r row) error {
+	if !w.jsonLines && w.jsonOpen && w.chunkSize > 0 && w.jsonRows >= w.chunkSize {
+		w.str.WriteString("\n]\n")
+		w.jsonOpen = false
+	}
+	switch {
+	case w.jsonLines:
+		if err := w.openJSONLines(&w.str); err != nil {
+			w.str.Reset()
+			return err
+		}
+	case !w.jsonOpen:
+		w.str.WriteString("[\n")
+		w.jsonOpen = true
+		w.jsonRows = 0
+	default:
+		w.str.WriteString(",\n")
+	}
+	w.jsonRows++
+	w.renderJSON(&w.str, r.values)
+	if w.jsonLines {
+		w.str.WriteString("\n")
+	}
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// openJSONLines renders the line set with WithJSONLMeta into b before the
+// first JSON Line, if needed
+func (w *Writer) openJSONLines(b *strings.Builder) error {
+	if w.jsonOpen {
+		return nil
+	}
+	w.jsonOpen = true
+	if w.jsonMeta == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(map[string]interface{}{"_meta": w.jsonMeta}); err != nil {
+		return fmt.Errorf("error encoding meta: %s", err)
+	}
+	b.WriteString(buf.String())
+	return nil
+}
+
+// closeJSON terminates the streamed JSON array, which is empty if no records
+// were written. JSON Lines get the line set with WithJSONLMeta if no records
+// were written.
+func (w *Writer) closeJSON() error {
+	if w.format == JSONFormat && w.jsonLines && !w.jsonOpen {
+		var b strings.Builder
+		if err := w.openJSONLines(&b); err != nil {
+			return err
+		}
+		if _, err := w.strw.WriteString(b.String()); err != nil {
+			return err
+		}
+		return w.strw.Flush()
+	}
+	if w.format != JSONFormat || w.jsonLines || w.jsonColumnar || w.jsonClosed {
+		return nil
+	}
+	w.jsonClosed = true
+	closing := "\n]\n"
+	if !w.jsonOpen {
+		closing = "[]\n"
+	}
+	if _, err := w.strw.WriteString(closing); err != nil {
+		return err
+	}
+	return w.strw.Flush()
+}
+
+// countingLines returns whether JSON Lines are preceded by their count, as
+// set by WithCountHeader
+func (w *Writer) countingLines() bool {
+	return w.countHeader && w.jsonLines && w.format == JSONFormat
+}
+
+// writeCountHeader writes the line holding the number of records in rows
+func (w *Writer) writeCountHeader(rows []row) error {
+	n := 0
+	for _, r := range rows {
+		if !r.separator && !r.section {
+			n++
+		}
+	}
+	var b strings.Builder
+	if err := w.openJSONLines(&b); err != nil {
+		return err
+	}
+	b.WriteString(`{"count":` + strconv.Itoa(n) + "}\n")
+	_, err := w.strw.WriteString(b.String())
+	return err
+}
+
+// writeJSONColumnar writes rows as a single JSON object mapping each column to
+// the array of its values
+func (w *Writer) writeJSONColumnar(rows []row) {
+	w.str.WriteString("{")
+	for i, col := range w.columns {
+		if i > 0 {
+			w.str.WriteString(",")
+		}
+		w.str.WriteString(jsonString(col))
+		w.str.WriteString(":[")
+		n := 0
+		for _, r := range rows {
+			if r.separator {
+				continue
+			}
+			if n > 0 {
+				w.str.WriteString(",")
+			}
+			n++
+			w.str.WriteString(w.jsonValue(col, r.values[i]))
+		}
+		w.str.WriteString("]")
+	}
+	w.str.WriteString("}\n")
+	w.strw.WriteString(w.str.String())
+	w.str.Reset()
+}
@@ -0,0 +1,243 @@
+package multiwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSONChunkSize(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, JSONFormat, WithChunkSize(2))
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if err := w.Write([]string{id}); err != nil {
+			t.Fatal(err)
+		}
+		if id == "3" {
+			w.Flush()
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	var sizes []int
+	for {
+		var chunk []map[string]string
+		if err := dec.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("got invalid JSON chunk: %v", err)
+		}
+		sizes = append(sizes, len(chunk))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("got chunks of %v records, want [2 2 1]", sizes)
+	}
+}
+
+func TestCountHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, NDJSONFormat, WithCountHeader(true))
+	for _, id := range []string{"1", "2"} {
+		if err := w.Write([]string{id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Fatalf("got %q before Close, want the records held", buf.String())
+	}
+	if err := w.Write([]string{"3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"count\":3}\n{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONLMeta(t *testing.T) {
+	meta := map[string]interface{}{"source": "db", "version": 2}
+	tests := []struct {
+		name    string
+		opts    []Option
+		records int
+		want    string
+	}{
+		{
+			name:    "records",
+			records: 2,
+			want:    "{\"_meta\":{\"source\":\"db\",\"version\":2}}\n{\"id\":\"0\"}\n{\"id\":\"1\"}\n",
+		},
+		{
+			name: "empty",
+			want: "{\"_meta\":{\"source\":\"db\",\"version\":2}}\n",
+		},
+		{
+			name:    "count",
+			opts:    []Option{WithCountHeader(true)},
+			records: 1,
+			want:    "{\"_meta\":{\"source\":\"db\",\"version\":2}}\n{\"count\":1}\n{\"id\":\"0\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id"}, NDJSONFormat, append(tt.opts, WithJSONLMeta(meta))...)
+			for i := 0; i < tt.records; i++ {
+				if err := w.Write([]string{strconv.Itoa(i)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPreserveColumnOrder(t *testing.T) {
+	for _, format := range []string{JSONFormat, NDJSONFormat} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"zeta", "alpha", "mid"}, format, WithJSONPreserveColumnOrder(true))
+			if err := w.Write([]string{"1", "2", "3"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			want := `{"zeta":"1","alpha":"2","mid":"3"}`
+			if got := buf.String(); !strings.Contains(got, want) {
+				t.Errorf("got %q, want keys in column order as in %s", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONValid(t *testing.T) {
+	columns := []string{"id", `we"ird`, "note"}
+	records := [][]string{
+		{"1", "<b>&</b>", `say "hi"`},
+		{"2", "tab\there", "line\nbreak sep"},
+		{"3", "\x00\x1f", "bad \xff utf8"},
+	}
+	for _, tt := range []struct {
+		name    string
+		records [][]string
+		opts    []Option
+	}{
+		{"records", records, nil},
+		{"flushed", records, []Option{WithMaxBufferedRows(1)}},
+		{"chunked", records, []Option{WithChunkSize(2)}},
+		{"empty", nil, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, columns, JSONFormat, tt.records, tt.opts...)
+			dec := json.NewDecoder(strings.NewReader(got))
+			n := 0
+			for {
+				var arr []map[string]string
+				if err := dec.Decode(&arr); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("got invalid JSON %q: %s", got, err)
+				}
+				for _, obj := range arr {
+					if obj["id"] != strconv.Itoa(n+1) {
+						t.Errorf("got id %q, want %d", obj["id"], n+1)
+					}
+					n++
+				}
+			}
+			if n != len(tt.records) {
+				t.Errorf("got %d objects, want %d", n, len(tt.records))
+			}
+		})
+	}
+}
+
+func TestJSONLinesValid(t *testing.T) {
+	records := [][]string{
+		{"1", "<b>&</b>", `say "hi"`},
+		{"2", "tab\there", "line\nbreak sep"},
+		{"3", "\x00\x1f", "bad \xff utf8"},
+	}
+	got := writeRecords(t, []string{"id", `we"ird`, "note"}, NDJSONFormat, records)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != len(records) {
+		t.Fatalf("got %d lines, want %d in %q", len(lines), len(records), got)
+	}
+	for i, line := range lines {
+		var obj map[string]string
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			t.Fatalf("got invalid JSON line %q: %s", line, err)
+		}
+		if obj[`we"ird`] != records[i][1] {
+			t.Errorf("got %q, want %q", obj[`we"ird`], records[i][1])
+		}
+	}
+}
+
+func TestJSONColumnar(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, JSONFormat, WithJSONColumnar(true), WithColumnType("id", Int))
+	w.Write([]string{"1", "Bob"})
+	w.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("got %q after Flush, want records held until Close", buf.String())
+	}
+	w.Write([]string{"2", `Sally "S"`})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"id":[1,2],"name":["Bob","Sally \"S\""]}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	var cols map[string][]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &cols); err != nil {
+		t.Fatal(err)
+	}
+
+	got := writeRecords(t, []string{"id", "name"}, JSONFormat, nil, WithJSONColumnar(true))
+	if want := `{"id":[],"name":[]}` + "\n"; got != want {
+		t.Errorf("got %q without records, want %q", got, want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	records := [][]string{{"1", "alice"}, {"2", "bob"}}
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, NDJSONFormat)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"id\":\"1\",\"name\":\"alice\"}\n{\"id\":\"2\",\"name\":\"bob\"}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the same as JSONFormat with WithJSONLines
+	if got := writeRecords(t, []string{"id", "name"}, JSONFormat, records, WithJSONLines()); got != want {
+		t.Errorf("got %q with WithJSONLines, want %q", got, want)
+	}
+	// no records write nothing rather than an empty array
+	if got := writeRecords(t, []string{"id"}, NDJSONFormat, nil); got != "" {
+		t.Errorf("got %q without records, want no output", got)
+	}
+}
@@ -0,0 +1,31 @@
+package multiwriter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// writeLogfmt writes the row as a single logfmt line
+func (w *Writer) writeLogfmt(r row) error {
+	w.renderLogfmt(&w.str, r.values)
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderLogfmt renders values into b as a single line of space-separated
+// key=value pairs, quoting values that contain spaces, equals signs, quotes or
+// control characters
+func (w *Writer) renderLogfmt(b *strings.Builder, values []string) {
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if strings.ContainsAny(v, " =\"\\") || strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(w.columns[i] + "=" + v)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,29 @@
+package multiwriter
+
+import (
+	"testing"
+)
+
+func TestLogfmtQuoting(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "v=plain\n"},
+		{"", "v=\n"},
+		{"two words", "v=\"two words\"\n"},
+		{"a=b", "v=\"a=b\"\n"},
+		{`say "hi"`, "v=\"say \\\"hi\\\"\"\n"},
+		{`C:\tmp`, "v=\"C:\\\\tmp\"\n"},
+		{"a\tb", "v=\"a\\tb\"\n"},
+		{"a\nb", "v=\"a\\nb\"\n"},
+		{"a\x00b", "v=\"a\\x00b\"\n"},
+		{"héllo", "v=héllo\n"},
+	}
+	for _, tt := range tests {
+		got := writeRecords(t, []string{"v"}, LogfmtFormat, [][]string{{tt.value}})
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
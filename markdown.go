@@ -0,0 +1,55 @@
+package multiwriter
+
+import (
+	"fmt"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// markdownEscaper escapes characters that would break a Markdown table cell
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// writeMarkdown writes the row as a single Markdown table row
+func (w *Writer) writeMarkdown(r row) error {
+	renderMarkdownRow(&w.str, r.values)
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// writeMarkdownHeader writes the header row holding names and the delimiter
+// row of a Markdown table
+func (w *Writer) writeMarkdownHeader(names []string) {
+	w.renderMarkdownHeader(&w.str, names)
+	if _, err := w.strw.WriteString(w.str.String()); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error writing header to markdown: %s", err))
+	}
+	w.str.Reset()
+}
+
+// renderMarkdownHeader renders the header row holding names and the delimiter
+// row of a Markdown table into b, aligning Int and Float columns to the right
+func (w *Writer) renderMarkdownHeader(b *strings.Builder, names []string) {
+	renderMarkdownRow(b, names)
+	delims := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		switch w.types[col] {
+		case Int, Float:
+			delims[i] = "---:"
+		default:
+			delims[i] = "---"
+		}
+	}
+	b.WriteString("| " + strings.Join(delims, " | ") + " |\n")
+}
+
+// renderMarkdownRow renders values into b as a Markdown table row, escaping
+// pipes and replacing newlines with line breaks
+func renderMarkdownRow(b *strings.Builder, values []string) {
+	b.WriteString("|")
+	for _, v := range values {
+		b.WriteString(" " + markdownEscaper.Replace(v) + " |")
+	}
+	b.WriteString("\n")
+}
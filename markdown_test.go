@@ -0,0 +1,25 @@
+package multiwriter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownPipeEscaping(t *testing.T) {
+	records := [][]string{{"a|b", "x"}, {"||", "two\nlines"}, {"", "crlf\r\nend"}}
+	got := writeRecords(t, []string{"id|key", "note"}, MarkdownFormat, records)
+	want := "| id\\|key | note |\n" +
+		"| --- | --- |\n" +
+		"| a\\|b | x |\n" +
+		"| \\|\\| | two<br>lines |\n" +
+		"|  | crlf<br>end |\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// every row keeps as many cells as there are columns
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if n := strings.Count(strings.ReplaceAll(line, `\|`, ""), "|"); n != 3 {
+			t.Errorf("got %d unescaped pipes in %q, want 3", n, line)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package multiwriter
+
+// merge folds records sharing the same key column into one
+type merge struct {
+	keyCol  string
+	combine func(existing, incoming []string) []string
+}
+
+// mergeDuplicates combines rows with the same key into the first row with that
+// key, re-formatting the combined values
+func (w *Writer) mergeDuplicates(rows []row) []row {
+	ki := w.columnIndex(w.merge.keyCol)
+	if ki < 0 {
+		return rows
+	}
+	seen := map[string]int{}
+	merged := map[int]bool{}
+	out := rows[:0]
+	for _, r := range rows {
+		if r.separator || ki >= len(r.raw) {
+			out = append(out, r)
+			continue
+		}
+		i, ok := seen[r.raw[ki]]
+		if !ok {
+			seen[r.raw[ki]] = len(out)
+			out = append(out, r)
+			continue
+		}
+		out[i].raw = w.merge.combine(out[i].raw, r.raw)
+		merged[i] = true
+	}
+	for i := range merged {
+		values, err := w.encodeRecord(w.formatRecord(out[i].raw))
+		if err != nil {
+			w.recordError(out[i].index, out[i].raw, err)
+			continue
+		}
+		out[i].values = values
+	}
+	return out
+}
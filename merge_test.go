@@ -0,0 +1,23 @@
+package multiwriter
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	sum := func(existing, incoming []string) []string {
+		a, _ := strconv.Atoi(existing[1])
+		b, _ := strconv.Atoi(incoming[1])
+		return []string{existing[0], strconv.Itoa(a + b)}
+	}
+	records := [][]string{{"b", "1"}, {"a", "2"}, {"b", "3"}, {"c", "4"}, {"b", "5"}}
+	double := FuncFormatter(func(v string) string { return v + v })
+	got := writeRecords(t, []string{"key", "count"}, CSVFormat, records,
+		WithMergeDuplicates("key", sum), WithFormatter("key", double))
+	// merged records are formatted from the combined raw values and keep
+	// the place of the first record with their key
+	if want := "key,count\nbb,9\naa,2\ncc,4\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
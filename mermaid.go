@@ -0,0 +1,63 @@
+package multiwriter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MermaidTable renders records as a grid of blocks in a block-beta diagram
+	MermaidTable = "table"
+	// MermaidFlowchart renders records as flowchart nodes, which is not
+	// supported yet
+	MermaidFlowchart = "flowchart"
+)
+
+// mermaidEscaper escapes characters that would end a quoted Mermaid label
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", "<br>")
+
+// writeMermaid writes the row as a line of blocks, opening the fenced Mermaid
+// block with the header first if needed
+func (w *Writer) writeMermaid(r row) error {
+	if w.mermaidKind != "" && w.mermaidKind != MermaidTable {
+		return fmt.Errorf("unsupported mermaid kind %q", w.mermaidKind)
+	}
+	if !w.mermaidOpen {
+		w.renderMermaidOpen(&w.str)
+		if !w.noHeader {
+			renderMermaidBlocks(&w.str, "h", w.headerNames())
+		}
+		w.mermaidOpen = true
+	}
+	renderMermaidBlocks(&w.str, fmt.Sprintf("r%d", w.mermaidRows), r.values)
+	w.mermaidRows++
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderMermaidOpen renders the opening of a fenced Mermaid block into b
+func (w *Writer) renderMermaidOpen(b *strings.Builder) {
+	b.WriteString("```mermaid\nblock-beta\n")
+	b.WriteString(fmt.Sprintf("  columns %d\n", len(w.columns)))
+}
+
+// renderMermaidBlocks renders a line of labelled blocks with ids prefixed by
+// id into b
+func renderMermaidBlocks(b *strings.Builder, id string, values []string) {
+	b.WriteString(" ")
+	for i, v := range values {
+		b.WriteString(fmt.Sprintf(" %sc%d[\"%s\"]", id, i, mermaidEscaper.Replace(v)))
+	}
+	b.WriteString("\n")
+}
+
+// closeMermaid closes the fenced Mermaid block if one is open
+func (w *Writer) closeMermaid() {
+	if !w.mermaidOpen {
+		return
+	}
+	w.strw.WriteString("```\n")
+	w.mermaidOpen = false
+	w.mermaidRows = 0
+}
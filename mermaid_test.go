@@ -0,0 +1,29 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMermaid(t *testing.T) {
+	records := [][]string{{"1", `say "hi"`}, {"2", "two\nlines"}}
+	got := writeRecords(t, []string{"id", "note"}, MermaidFormat, records)
+	want := "```mermaid\nblock-beta\n  columns 2\n" +
+		"  hc0[\"id\"] hc1[\"note\"]\n" +
+		"  r0c0[\"1\"] r0c1[\"say #quot;hi#quot;\"]\n" +
+		"  r1c0[\"2\"] r1c1[\"two<br>lines\"]\n" +
+		"```\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMermaidUnsupportedKind(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, MermaidFormat, WithMermaidKind(MermaidFlowchart))
+	err := w.Write([]string{"1"})
+	if err == nil || !strings.Contains(err.Error(), `unsupported mermaid kind "flowchart"`) {
+		t.Errorf("got %v, want an unsupported kind error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package multiwriter
+
+import (
+	"io"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Dest is an output of a Multi along with its format and any options that
+// apply to it alone, such as its own formatters
+type Dest struct {
+	Writer  io.Writer
+	Format  string
+	Options []Option
+}
+
+// Multi fans records out to several destinations, each with its own format
+type Multi struct {
+	writers []*Writer
+}
+
+// NewMulti returns a new Multi writing records with the given columns to
+// every destination. opts apply to all destinations, followed by the
+// destination's own options.
+func NewMulti(dests []Dest, columns []string, opts ...Option) *Multi {
+	m := &Multi{}
+	for _, d := range dests {
+		destOpts := append(append([]Option(nil), opts...), d.Options...)
+		m.writers = append(m.writers, New(d.Writer, columns, d.Format, destOpts...))
+	}
+	return m
+}
+
+// Writers returns the writer of each destination, in order
+func (m *Multi) Writers() []*Writer {
+	return m.writers
+}
+
+// Write writes the record to every destination, returning the errors of the
+// destinations that failed
+func (m *Multi) Write(record []string) error {
+	var err error
+	for _, w := range m.writers {
+		if werr := w.Write(record); werr != nil {
+			err = multierror.Append(err, werr)
+		}
+	}
+	return err
+}
+
+// Flush flushes every destination
+func (m *Multi) Flush() {
+	for _, w := range m.writers {
+		w.Flush()
+	}
+}
+
+// Close closes every destination and returns their errors
+func (m *Multi) Close() error {
+	var err error
+	for _, w := range m.writers {
+		if werr := w.Close(); werr != nil {
+			err = multierror.Append(err, werr)
+		}
+	}
+	return err
+}
+
+// Error returns the errors of every destination
+func (m *Multi) Error() error {
+	var err error
+	for _, w := range m.writers {
+		if werr := w.Error(); werr != nil {
+			err = multierror.Append(err, werr)
+		}
+	}
+	return err
+}
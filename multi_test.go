@@ -0,0 +1,54 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMultiFanOut(t *testing.T) {
+	var csvOut, jsonOut bytes.Buffer
+	m := NewMulti([]Dest{
+		{Writer: &csvOut, Format: CSVFormat},
+		{Writer: &jsonOut, Format: NDJSONFormat, Options: []Option{WithFormatter("name", FuncFormatter(strings.ToUpper))}},
+	}, []string{"id", "name"}, WithHeaderLabels(map[string]string{"id": "ID"}))
+	if err := m.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := csvOut.String(), "ID,name\n1,alice\n"; got != want {
+		t.Errorf("got CSV %q, want %q", got, want)
+	}
+	if got, want := jsonOut.String(), "{\"id\":\"1\",\"name\":\"ALICE\"}\n"; got != want {
+		t.Errorf("got NDJSON %q, want %q", got, want)
+	}
+	if got := len(m.Writers()); got != 2 {
+		t.Errorf("got %d writers, want 2", got)
+	}
+}
+
+func TestMultiErrors(t *testing.T) {
+	var good bytes.Buffer
+	m := NewMulti([]Dest{
+		{Writer: &good, Format: CSVFormat},
+		{Writer: &failingWriter{}, Format: CSVFormat},
+		{Writer: &bytes.Buffer{}, Format: PromFormat},
+	}, []string{"id", "value"})
+	// Prom fails the record without a metric, while the others still get it
+	err := m.Write([]string{"1", "x"})
+	if err == nil || !strings.Contains(err.Error(), "no metric configured") {
+		t.Errorf("got %v, want the Prom destination's error", err)
+	}
+	err = m.Close()
+	if err == nil || !strings.Contains(err.Error(), "no metric configured") || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("got %v, want the errors of both failing destinations", err)
+	}
+	if got, want := good.String(), "id,value\n1,x\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if m.Error() == nil {
+		t.Error("got nil from Error, want the destinations' errors")
+	}
+}
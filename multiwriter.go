@@ -2,13 +2,27 @@ package multiwriter // import "go.enc.dev/multiwriter"
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"hash"
+	"html/template"
 	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/kataras/tablewriter"
+	"golang.org/x/text/encoding"
 )
 
 const (
@@ -20,6 +34,63 @@ const (
 	TableFormat = "table"
 	// TextFormat sets the output format to a fmt-renderd text string
 	TextFormat = "text"
+	// PromFormat sets the output format to Prometheus text exposition lines,
+	// see WithPromMetric
+	PromFormat = "prom"
+	// LogfmtFormat sets the output format to logfmt key=value lines
+	LogfmtFormat = "logfmt"
+	// MermaidFormat sets the output format to a fenced Mermaid diagram, see
+	// WithMermaidKind
+	MermaidFormat = "mermaid"
+	// JSONFormat sets the output format to a streamed JSON array of objects
+	// keyed by column name, or JSON Lines with WithJSONLines
+	JSONFormat = "json"
+	// PGCopyFormat sets the output format to tab-separated Postgres COPY text
+	// data, without a header, to be loaded with COPY ... FROM STDIN
+	PGCopyFormat = "pgcopy"
+	// MarkdownFormat sets the output format to a GitHub-flavored Markdown table
+	MarkdownFormat = "markdown"
+	// NDJSONFormat sets the output format to newline-delimited JSON objects,
+	// the same as JSONFormat with WithJSONLines
+	NDJSONFormat = "ndjson"
+	// TSVFormat sets the output format to tab-separated values, the same as
+	// CSVFormat with WithDelimiter('\t')
+	TSVFormat = "tsv"
+	// YAMLFormat sets the output format to a YAML sequence of mappings keyed
+	// by column name
+	YAMLFormat = "yaml"
+	// HTMLFormat sets the output format to an HTML table, see
+	// WithHTMLTemplate
+	HTMLFormat = "html"
+	// XLSXFormat sets the output format to an Excel workbook with a single
+	// worksheet, which holds rows in memory and is written on Close
+	XLSXFormat = "xlsx"
+)
+
+// NewlineStyle is a line ending convention used to normalize embedded
+// newlines. Any other string replaces each line ending, e.g. " " to strip
+// them.
+type NewlineStyle string
+
+const (
+	// NewlineLF normalizes embedded newlines to \n
+	NewlineLF NewlineStyle = "\n"
+	// NewlineCRLF normalizes embedded newlines to \r\n
+	NewlineCRLF NewlineStyle = "\r\n"
+)
+
+// ColumnType declares the kind of data held by a column
+type ColumnType string
+
+const (
+	// String is the default column type
+	String ColumnType = "string"
+	// Int declares a column of integers
+	Int ColumnType = "int"
+	// Float declares a column of floating point numbers
+	Float ColumnType = "float"
+	// Bool declares a column of booleans
+	Bool ColumnType = "bool"
 )
 
 // Formatter is a dumb way to inject custom formatting logic for column data.
@@ -29,6 +100,29 @@ type Formatter interface {
 	Format(string) string
 }
 
+// RecordFormatter is a Formatter that can see the whole record, e.g. to format
+// a value based on another column. When a registered formatter implements it,
+// FormatRecord is called instead of Format with the column name, the value
+// formatted so far and the raw record.
+type RecordFormatter interface {
+	Formatter
+	FormatRecord(column string, value string, record []string) string
+}
+
+// RecordFuncFormatter wraps a user-defined function to apply formatting based
+// on the whole record
+type RecordFuncFormatter func(column string, value string, record []string) string
+
+// Format formats the value by calling the external function without a record
+func (rf RecordFuncFormatter) Format(value string) string {
+	return rf("", value, nil)
+}
+
+// FormatRecord formats the value by calling the external function
+func (rf RecordFuncFormatter) FormatRecord(column string, value string, record []string) string {
+	return rf(column, value, record)
+}
+
 // BasicFormatter uses fmt.Sprintf to format based on a static format string
 type BasicFormatter struct {
 	FmtString string
@@ -47,21 +141,306 @@ func (ff FuncFormatter) Format(value string) string {
 	return ff(value)
 }
 
+// MaxBytesFormatter truncates values so their UTF-8 encoding is at most Max
+// bytes long, without splitting a multibyte rune. This is useful when the
+// output targets a database column with a byte limit.
+type MaxBytesFormatter struct {
+	Max int
+}
+
+// Format truncates value to at most Max bytes on a rune boundary
+func (mf MaxBytesFormatter) Format(value string) string {
+	if len(value) <= mf.Max {
+		return value
+	}
+	if mf.Max <= 0 {
+		return ""
+	}
+	cut := mf.Max
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// BaseFormatter converts integer values from base From to base To, e.g. from
+// decimal to hexadecimal, with an optional Prefix such as "0x". Values that
+// are not integers in base From are passed through unchanged.
+type BaseFormatter struct {
+	From   int
+	To     int
+	Prefix string
+}
+
+// Format converts value from base From to base To
+func (bf BaseFormatter) Format(value string) string {
+	if bf.To < 2 || bf.To > 36 {
+		return value
+	}
+	n, err := strconv.ParseInt(value, bf.From, 64)
+	if err != nil {
+		return value
+	}
+	if n < 0 {
+		// -n overflows for the minimum int64 but converts to the right
+		// magnitude as a uint64
+		return "-" + bf.Prefix + strconv.FormatUint(uint64(-n), bf.To)
+	}
+	return bf.Prefix + strconv.FormatInt(n, bf.To)
+}
+
+// ErrClosed is returned when writing to a Writer that has been closed
+var ErrClosed = errors.New("writer is closed")
+
+// ErrUnknownFormat is reported by Error and returned by Write when New is
+// given a format that isn't one of AllFormats
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ErrRecordLengthMismatch is wrapped by the errors for records whose number of
+// fields doesn't match the columns
+var ErrRecordLengthMismatch = errors.New("record length mismatch")
+
+// ErrColumnsLocked is wrapped by the errors for column changes prevented by
+// WithLockColumns
+var ErrColumnsLocked = errors.New("columns are locked")
+
 // AllFormats contains all the formats supported
-var AllFormats = []string{CSVFormat, TableFormat, TextFormat}
+var AllFormats = []string{CSVFormat, TableFormat, TextFormat, PromFormat, LogfmtFormat, MermaidFormat, JSONFormat, PGCopyFormat, MarkdownFormat, NDJSONFormat, TSVFormat, YAMLFormat, HTMLFormat, XLSXFormat}
+
+// extensionFormats maps lowercase file extensions to the format they imply
+var extensionFormats = map[string]string{
+	".csv":      CSVFormat,
+	".txt":      TextFormat,
+	".text":     TextFormat,
+	".prom":     PromFormat,
+	".json":     JSONFormat,
+	".ndjson":   NDJSONFormat,
+	".jsonl":    NDJSONFormat,
+	".tsv":      TSVFormat,
+	".tab":      TSVFormat,
+	".md":       MarkdownFormat,
+	".markdown": MarkdownFormat,
+	".yaml":     YAMLFormat,
+	".yml":      YAMLFormat,
+	".html":     HTMLFormat,
+	".htm":      HTMLFormat,
+	".xlsx":     XLSXFormat,
+}
+
+// FormatFromExtension returns the format implied by the extension of path,
+// e.g. CSVFormat for "report.csv". Matching is case-insensitive and an error
+// is returned for unknown extensions.
+func FormatFromExtension(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	format, ok := extensionFormats[ext]
+	if !ok {
+		return "", fmt.Errorf("unknown format for extension %q", ext)
+	}
+	return format, nil
+}
+
+// FormatForExtension returns the format implied by name, which is either a
+// file name such as "data.2024.csv" or a bare extension such as ".md" or
+// "md". Only the last extension counts, matching is case-insensitive and an
+// error naming the input is returned for unknown extensions.
+func FormatForExtension(name string) (string, error) {
+	ext := filepath.Ext(name)
+	if !strings.Contains(name, ".") {
+		ext = "." + name
+	}
+	format, ok := extensionFormats[strings.ToLower(ext)]
+	if !ok {
+		return "", fmt.Errorf("unknown format for %q", name)
+	}
+	return format, nil
+}
 
-// Writer writes structured data to an internal buffer and outputs it as a given format when flushed
+// ansiEscape matches ANSI CSI sequences, e.g. colors, and OSC sequences, e.g.
+// hyperlinks
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)`)
+
+// Writer writes structured data to an internal buffer and outputs it as a given format when flushed.
+// It is safe for concurrent use.
 type Writer struct {
+	// mu serializes access to the buffers and state below
+	mu         sync.Mutex
 	size       int
+	dest       io.Writer
 	basew      io.Writer
 	csvw       *csv.Writer
 	table      *tablewriter.Table
 	str        strings.Builder
 	strw       *bufio.Writer
 	formatters map[string][]Formatter
-	columns    []string
-	format     string
-	err        error
+	caches     map[string]*lruCache
+	// formattersAt holds formatters registered by column index, applied after
+	// those registered by name
+	formattersAt map[int][]Formatter
+	// encodings maps columns to the charset their values are written in, with
+	// "" applying to every column
+	encodings      map[string]encoding.Encoding
+	strictEncoding bool
+	types          map[string]ColumnType
+	columns        []string
+	format         string
+	trimFields     bool
+	stripANSI      bool
+	nullValue      *string
+	csvNull        *string
+	emptyValue     string
+	newline        NewlineStyle
+	typeHeader     bool
+	sheetTypes     map[string]string
+	pipe           func(io.Writer) io.Writer
+	pipew          io.Writer
+	onError        func(int, []string, error) bool
+	recordErrs     []RecordError
+	index          int
+	failed         int
+	closed         bool
+	finished       bool
+	done           bool
+	aborted        error
+	formatErr      error
+	stable         bool
+	sort           *sorter
+	rawSep         string
+	rawRecSep      string
+	rawEscaper     Formatter
+	csvQuoting     bool
+	csvEscape      rune
+	comma          rune
+	crlf           bool
+	rfc4180        bool
+	alignCSV       bool
+	prom           *promMetric
+	sidecar        io.Writer
+	subtotals      *subtotals
+	envelope       *envelopeWriter
+	pivot          *pivot
+	baseline       *baseline
+	lockCols       bool
+	merge          *merge
+	run            *runLength
+	appended       []appendedColumn
+	scratch        []string
+	transformers   []func([]string) ([]string, bool)
+	rowHash        *rowHash
+	reduce         *reduce
+	keepReduced    bool
+	// output names the projected columns, project holds their indices in the
+	// input records and inputColumns are the columns of the input records.
+	// exclude names columns left out of the output
+	output       []string
+	exclude      []string
+	project      []int
+	inputColumns []string
+	// mermaidKind is the kind of Mermaid diagram to render, mermaidOpen is
+	// set while a diagram block is open and mermaidRows counts its rows
+	mermaidKind string
+	mermaidOpen bool
+	mermaidRows int
+	// headerState is shared with other writers to emit a single header, and
+	// headerDecided is set once the first flush has claimed it or not
+	headerState   *HeaderState
+	headerDecided bool
+	// dynamic is set by WithDynamicColumns, and columnsFixed once the first
+	// flush has written the header
+	dynamic      bool
+	columnsFixed bool
+	// jsonLines emits JSON Lines instead of an array, jsonNumbers emits
+	// numeric values as JSON numbers, jsonOpen and jsonClosed track
+	// whether the array has been opened and terminated, and jsonRows counts
+	// the records in the open array
+	jsonLines   bool
+	jsonNumbers bool
+	jsonOpen    bool
+	jsonClosed  bool
+	jsonRows    int
+	// htmlOpen is set while an HTML table is open
+	htmlOpen     bool
+	htmlTemplate *template.Template
+	htmlCaption  string
+	htmlScope    bool
+	textTemplate *texttemplate.Template
+	// jsonColumnar holds rows until Close to write them as column arrays
+	jsonColumnar bool
+	// countHeader holds JSON Lines rows until Close to write their count first
+	countHeader bool
+	jsonMeta    map[string]interface{}
+	// total is the grand total footer computed by the last flush of rows
+	total []string
+	// headerPending is set when the CSV header is deferred to the first flush
+	headerPending bool
+	quoteText     bool
+	rawValues     bool
+	tableStyle    tableStyle
+	maxWidths     map[string]int
+	truncate      TruncatePolicy
+	colorMode     ColorMode
+	noColor       bool
+	stream        *streamTable
+	custom        Format
+	transpose     bool
+	transposed    int
+	textHeader    bool
+	noHeader      bool
+	appendMode    bool
+	closeDest     bool
+	headerLabels  map[string]string
+	opts          []Option
+	sink          *funcWriter
+	chunkSize     int
+	footerFunc    func(int, map[string]Stats) []string
+	footerAggs    map[string]func([]string) string
+	footerValues  map[string][]string
+	footerStats   map[string]func(Stats) float64
+	stats         map[string]*Stats
+	rowCount      int
+	validUTF8     bool
+	utf8Repl      rune
+	tail          int
+	withStats     bool
+	datasetCol    string
+	groupSets     bool
+	groupBy       *grouping
+	paging        *paging
+	summary       io.Writer
+	counter       *countingWriter
+	start         time.Time
+	// repeatHeader is the number of rows after which Table and Text formats
+	// re-emit the header, and sinceHeader counts rows since it was last emitted
+	repeatHeader int
+	sinceHeader  int
+	// pending approximates the bytes written since the last flush,
+	// tableOpen is set while a Table has been rendered without its bottom
+	// border, and tableDone once it has been rendered with it
+	pending     int
+	pendingRows int
+	maxRows     int
+	maxBytes    int
+	flushRate   time.Duration
+	lastFlush   time.Time
+	tableOpen   bool
+	tableDone   bool
+	rows        []row
+	err         error
+}
+
+// row is a formatted record along with its raw value and write index
+type row struct {
+	index     int
+	raw       []string
+	values    []string
+	separator bool
+	// section is set for the header of the group of rows that follows, set
+	// by WithGroupBy
+	section bool
+}
+
+// flusher is implemented by output writers that buffer internally
+type flusher interface {
+	Flush() error
 }
 
 // Option modifies default options of the Writer
@@ -79,95 +458,2099 @@ func WithFormatter(column string, f Formatter) Option {
 	}
 }
 
-// WithSize modifies the size of the internal buffer
-func WithSize(size int) Option {
+// WithFormatterAt sets the text formatting for the column at index, for
+// columns that are generated or share a name with another column. Indexes
+// refer to the output columns and out of range indexes are reported by Error.
+func WithFormatterAt(index int, f Formatter) Option {
 	return func(w *Writer) {
-		w.size = size
+		if w.formattersAt == nil {
+			w.formattersAt = map[int][]Formatter{}
+		}
+		w.formattersAt[index] = append(w.formattersAt[index], f)
 	}
 }
 
-// New returns a new Writer for writing. Format should be one of AllFormats.
-// The size value determines how big the internal buffer should be. When the
-// buffer fills, the writer automatically flushes it.
-func New(writer io.Writer, columns []string, format string, opts ...Option) *Writer {
-	table := tablewriter.NewWriter(writer)
-	table.SetHeader(columns)
-	csvw := csv.NewWriter(writer)
-	csvw.Write(columns)
-	w := &Writer{
-		basew:      writer,
-		size:       defaultSize,
-		csvw:       csvw,
-		table:      table,
-		formatters: map[string][]Formatter{},
-		columns:    columns,
-		format:     format,
+// WithConditionalFormatter adds f to the column's formatters, applied only to
+// records for which cond returns true, e.g. to color a size red when the
+// status is "failed". cond is passed the raw record in the order of the
+// writer's columns.
+func WithConditionalFormatter(column string, cond func(record []string) bool, f Formatter) Option {
+	return func(w *Writer) {
+		WithFormatter(column, RecordFuncFormatter(func(col string, value string, record []string) string {
+			if record == nil || !cond(record) {
+				return value
+			}
+			return w.applyFormatter(f, col, value, record)
+		}))(w)
 	}
-	for _, o := range opts {
-		o(w)
+}
+
+// WithFormatterCache memoizes the output of the column's formatters in an LRU
+// cache of the given size keyed by input value, so expensive formatters are
+// not recomputed for repeated values. Formatters must be deterministic.
+func WithFormatterCache(column string, size int) Option {
+	return func(w *Writer) {
+		w.caches[column] = newLRUCache(size)
 	}
-	w.strw = bufio.NewWriterSize(writer, w.size)
-	return w
 }
 
-// Write writes the record to the internal buffer
-func (w *Writer) Write(record []string) error {
-	recordFormatted := w.formatRecord(record)
-	switch w.format {
-	case CSVFormat:
-		if err := w.csvw.Write(recordFormatted); err != nil {
-			w.err = multierror.Append(w.err, fmt.Errorf("error writing record to csv: %s", err))
-			return err
-		}
-	case TableFormat:
-		w.table.Append(recordFormatted)
-	case TextFormat:
-		w.str.WriteString("---\n")
-		for i, v := range recordFormatted {
-			w.str.WriteString(fmt.Sprintf("%s: %s\n", w.columns[i], v))
+// WithColumnEncoding transcodes the column's values from UTF-8 to enc, e.g.
+// charmap.ISO8859_1, after formatting. An empty column applies enc to every
+// column. Characters enc can't represent are replaced with its replacement
+// character unless WithStrictEncoding is set.
+func WithColumnEncoding(column string, enc encoding.Encoding) Option {
+	return func(w *Writer) {
+		if w.encodings == nil {
+			w.encodings = map[string]encoding.Encoding{}
 		}
-		w.strw.WriteString(w.str.String())
-		w.str.Reset()
+		w.encodings[column] = enc
 	}
-	return nil
 }
 
-// Flush flushes all records from the internal buffer to its output writer
-func (w *Writer) Flush() {
-	switch w.format {
-	case CSVFormat:
-		w.csvw.Flush()
-		if err := w.csvw.Error(); err != nil {
-			w.err = multierror.Append(w.err, fmt.Errorf("error flushing csv: %s", err))
-		}
-		break
-	case TextFormat:
-		if err := w.strw.Flush(); err != nil {
-			w.err = multierror.Append(w.err, fmt.Errorf("error flushing text: %s", err))
-		}
-		w.strw.Reset(w.basew)
-		w.str.Reset()
-	case TableFormat:
-		w.table.Render()
-		w.table.ClearRows()
+// WithStrictEncoding fails records containing characters that can't be
+// represented in their column's encoding instead of replacing them
+func WithStrictEncoding(strict bool) Option {
+	return func(w *Writer) {
+		w.strictEncoding = strict
 	}
 }
 
-// Error returns whether there was an error writing.
-func (w *Writer) Error() error {
-	return w.err
+// WithTrimFields trims leading and trailing whitespace from every field before
+// it is formatted and written, regardless of the output format
+func WithTrimFields(trim bool) Option {
+	return func(w *Writer) {
+		w.trimFields = trim
+	}
 }
 
-// formatRecord applies column formatters to column values
-func (w *Writer) formatRecord(record []string) []string {
-	final := make([]string, len(record))
-	for i, val := range record {
-		colName := w.columns[i]
-		formatters := w.formatters[colName]
-		for _, formatter := range formatters {
-			val = formatter.Format(val)
-		}
-		final[i] = val
+// WithStripANSI removes ANSI escape sequences, such as terminal colors, from
+// every field before it is formatted and written
+func WithStripANSI(strip bool) Option {
+	return func(w *Writer) {
+		w.stripANSI = strip
+	}
+}
+
+// WithNullValue treats fields whose raw value equals value as null, which the
+// PGCopy format writes as \N
+func WithNullValue(value string) Option {
+	return func(w *Writer) {
+		w.nullValue = &value
+	}
+}
+
+// WithEmptyValue writes s, e.g. "N/A", in place of fields that are empty once
+// formatters have run, in every format
+func WithEmptyValue(s string) Option {
+	return func(w *Writer) {
+		w.emptyValue = s
+	}
+}
+
+// WithNewline normalizes newlines embedded within field values to the given
+// style before writing. This is independent of the record terminator, which is
+// controlled by the output format.
+func WithNewline(style NewlineStyle) Option {
+	return func(w *Writer) {
+		w.newline = style
+	}
+}
+
+// WithColumnType declares the type of data held by the column. Columns without
+// a declared type are treated as String.
+func WithColumnType(column string, t ColumnType) Option {
+	return func(w *Writer) {
+		w.types[column] = t
+	}
+}
+
+// WithTypeHeader writes a second CSV header line containing the declared type
+// of each column, e.g. string,int,float,string
+func WithTypeHeader(typeHeader bool) Option {
+	return func(w *Writer) {
+		w.typeHeader = typeHeader
+	}
+}
+
+// WithOutputPipe wraps the output writer in a user-defined transform, e.g.
+// encryption, compression or line prefixing, before any format writer touches
+// it. If the wrapper has a Flush method it is called on every Flush, and if it
+// is an io.Closer it is closed by Close.
+func WithOutputPipe(fn func(io.Writer) io.Writer) Option {
+	return func(w *Writer) {
+		w.pipe = fn
+	}
+}
+
+// WithOnError sets a callback invoked with the index, raw value and error of
+// every record that fails to write. Returning true skips the record and
+// continues, while returning false aborts so that all subsequent writes fail.
+// Errors handled by the callback are not aggregated into Error. The callback
+// runs while the writer is locked, so it must not call the writer's methods.
+func WithOnError(fn func(recordIndex int, record []string, err error) bool) Option {
+	return func(w *Writer) {
+		w.onError = fn
+	}
+}
+
+// WithStableOutput buffers records until Flush and emits them sorted by all
+// columns in order, so two exports of the same data produce byte-identical
+// output regardless of the order records were written in. Quoting in the CSV
+// format is already deterministic for identical values.
+func WithStableOutput() Option {
+	return func(w *Writer) {
+		w.stable = true
+	}
+}
+
+// WithRepeatHeaderEvery re-emits the header every n rows in the Table and Text
+// formats so long output remains readable when scrolled. The default of 0
+// never repeats the header.
+func WithRepeatHeaderEvery(n int) Option {
+	return func(w *Writer) {
+		w.repeatHeader = n
+	}
+}
+
+// WithNoHeader omits the header so that only records are written, e.g. when
+// appending to a file that already has one. This applies to every format,
+// including repeated headers and the Mermaid header blocks. Since a Markdown
+// table needs a header, its header row is left blank and followed by the
+// delimiter row, unless WithAppend is set.
+func WithNoHeader() Option {
+	return func(w *Writer) {
+		w.noHeader = true
+	}
+}
+
+// WithCloseUnderlying closes the output when the writer is closed, if it is an
+// io.Closer, e.g. a file that is only written through the writer. Outputs
+// replaced by ResetOutput or by the pages of WithPageOutput are closed as
+// well.
+func WithCloseUnderlying() Option {
+	return func(w *Writer) {
+		w.closeDest = true
+	}
+}
+
+// closeOutput closes the output if it is an io.Closer
+func (w *Writer) closeOutput() {
+	c, ok := w.dest.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := c.Close(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error closing output: %s", err))
+	}
+}
+
+// WithAppend omits the header, as WithNoHeader does, if the output already
+// has content, e.g. when periodic jobs append to the same file. The output's
+// size is taken from its Stat method, as for an *os.File; outputs without one
+// are treated as empty.
+func WithAppend() Option {
+	return func(w *Writer) {
+		w.appendMode = true
+	}
+}
+
+// WithHeaderLabels displays labels in place of the column names they are keyed
+// by in the header of the CSV, Table, Markdown, HTML and Mermaid formats and
+// the Text header line. Formats keyed by column name, such as JSON, keep the
+// column names, as do formatters and options that refer to columns.
+func WithHeaderLabels(labels map[string]string) Option {
+	return func(w *Writer) {
+		w.headerLabels = labels
+	}
+}
+
+// WithTextHeader writes a leading line listing the column names, and their
+// types if any are declared, before the records in the Text format
+func WithTextHeader(header bool) Option {
+	return func(w *Writer) {
+		w.textHeader = header
+	}
+}
+
+// WithPromMetric configures the PromFormat to emit a sample of the named metric
+// for each record, taking its value from valueCol and its labels from
+// labelCols. Records with a non-numeric value fail to write.
+func WithPromMetric(name, valueCol string, labelCols ...string) Option {
+	return func(w *Writer) {
+		w.prom = &promMetric{name: name, valueCol: valueCol, labelCols: labelCols}
+	}
+}
+
+// WithHeaderSidecar writes the header as a CSV record to out instead of the
+// main output, which then only contains data rows
+func WithHeaderSidecar(out io.Writer) Option {
+	return func(w *Writer) {
+		w.sidecar = out
+	}
+}
+
+// WithSubtotals buffers Table rows until Flush, sorts them by groupCol and
+// inserts a row after each group aggregating the numeric values of numericCol
+// with agg. A grand total is rendered as the table footer unless a footer
+// func is set.
+func WithSubtotals(groupCol, numericCol string, agg AggFunc) Option {
+	return func(w *Writer) {
+		w.subtotals = &subtotals{groupCol: groupCol, numericCol: numericCol, agg: agg}
+	}
+}
+
+// WithEnvelope writes prefix before the first output and suffix after the
+// final flush on Close, for embedding the output inside a larger document.
+// Both are written exactly once.
+func WithEnvelope(prefix, suffix []byte) Option {
+	return func(w *Writer) {
+		w.envelope = &envelopeWriter{prefix: prefix, suffix: suffix}
+	}
+}
+
+// WithPivot buffers records until Flush and reshapes them into a pivot for the
+// Table and CSV formats: the distinct values of rowCol become rows, the
+// distinct values of colCol become columns, and each intersection holds the
+// numeric values of valueCol aggregated with agg. The header is derived from
+// the data, so each flush emits a complete pivot with its own header.
+func WithPivot(rowCol, colCol, valueCol string, agg AggFunc) Option {
+	return func(w *Writer) {
+		w.pivot = &pivot{rowCol: rowCol, colCol: colCol, valueCol: valueCol, agg: agg}
+	}
+}
+
+// WithBaseline emits only the records that are new or changed compared to a
+// prior export, producing an incremental export. prior maps the value of
+// keyCol to the BaselineValue of the previously exported record. A ChangeColumn
+// is appended to the columns flagging each emitted record as ChangeAdded or
+// ChangeModified.
+func WithBaseline(prior map[string]string, keyCol string) Option {
+	return func(w *Writer) {
+		w.baseline = &baseline{prior: prior, keyCol: keyCol}
+		w.columns = append(append([]string(nil), w.columns...), ChangeColumn)
+		w.appended = append(w.appended, changeColumn)
+	}
+}
+
+// WithMergeDuplicates folds records sharing the same value in keyCol into one
+// by calling combine with the record written so far and the incoming raw
+// record, e.g. to sum counts. Records are held in memory until Flush and the
+// merged record takes the place of the first one with that key.
+func WithMergeDuplicates(keyCol string, combine func(existing, incoming []string) []string) Option {
+	return func(w *Writer) {
+		w.merge = &merge{keyCol: keyCol, combine: combine}
+	}
+}
+
+// WithColumns outputs only the given subset of columns, in the given order.
+// Records are still written with a field for every column passed to New and
+// are projected down to output, and formatters match the column names as
+// usual. Unknown output columns are reported by Error.
+func WithColumns(output []string) Option {
+	return func(w *Writer) {
+		w.output = output
+	}
+}
+
+// WithExcludeColumns outputs every column except the given ones, projecting
+// records as WithColumns does. Combined with WithColumns, the columns are
+// removed from its output. Unknown excluded columns are reported by Error.
+func WithExcludeColumns(exclude []string) Option {
+	return func(w *Writer) {
+		w.exclude = exclude
+	}
+}
+
+// WithRunLengthCount collapses runs of consecutive identical records into one
+// record with an added countHeader column holding the length of the run, e.g.
+// to turn sorted input into a frequency report. Records must be written
+// without the count column.
+func WithRunLengthCount(countHeader string) Option {
+	return func(w *Writer) {
+		w.run = &runLength{}
+		w.columns = append(append([]string(nil), w.columns...), countHeader)
+		w.appended = append(w.appended, countColumn)
+	}
+}
+
+// WithReduce folds the records written since the last flush into a single
+// summary record, starting from a copy of initial, which is written on Flush.
+// fn receives the accumulated record and the next raw record and returns the
+// new accumulated record, which must have a field for every column. The raw
+// records are not written unless WithKeepReducedRows is set.
+func WithReduce(fn func(acc, record []string) []string, initial []string) Option {
+	return func(w *Writer) {
+		w.reduce = &reduce{fn: fn, initial: initial}
+	}
+}
+
+// WithKeepReducedRows writes the raw records folded by WithReduce as well,
+// followed by the summary record
+func WithKeepReducedRows(keep bool) Option {
+	return func(w *Writer) {
+		w.keepReduced = keep
+	}
+}
+
+// WithRowHashColumn appends a header column holding the hex encoded hash of
+// each record's raw fields, computed with algo, e.g. sha256.New. The hash is
+// taken before any formatting so it is a stable identifier of the record's
+// content. Records must be written without the hash column.
+func WithRowHashColumn(header string, algo func() hash.Hash) Option {
+	return func(w *Writer) {
+		w.rowHash = &rowHash{algo: algo}
+		w.columns = append(append([]string(nil), w.columns...), header)
+		w.appended = append(w.appended, hashColumn)
+	}
+}
+
+// WithRowTransformer rewrites or drops whole records before they are
+// formatted and buffered, e.g. to filter rows on one column or derive a column
+// from others. fn receives the raw record and returns the record to write and
+// whether to keep it; the returned record must still have a field for every
+// column. Multiple transformers run in the order they are given.
+func WithRowTransformer(fn func(record []string) ([]string, bool)) Option {
+	return func(w *Writer) {
+		w.transformers = append(w.transformers, fn)
+	}
+}
+
+// WithLockColumns prevents the columns from changing once the writer is
+// created, so a long-running stream's schema stays stable. Any attempt to
+// change them afterwards fails with an error.
+func WithLockColumns() Option {
+	return func(w *Writer) {
+		w.lockCols = true
+	}
+}
+
+// WithMermaidKind sets the kind of diagram rendered by the MermaidFormat. Only
+// MermaidTable, the default, is supported for now.
+func WithMermaidKind(kind string) Option {
+	return func(w *Writer) {
+		w.mermaidKind = kind
+	}
+}
+
+// WithSharedHeaderState shares header emission with all other writers using
+// the same state, so that only the first of them to flush emits the header.
+// Records are buffered until the first flush.
+func WithSharedHeaderState(state *HeaderState) Option {
+	return func(w *Writer) {
+		w.headerState = state
+	}
+}
+
+// WithJSONLines makes the JSONFormat emit one JSON object per line instead of
+// a JSON array, which is better suited to large output
+func WithJSONLines() Option {
+	return func(w *Writer) {
+		w.jsonLines = true
+	}
+}
+
+// WithCountHeader writes a first line holding the number of records, e.g.
+// {"count":3}, before the records of the NDJSON format, for consumers that
+// want an upfront count. Since the count is only known once every record has
+// been written, all records are held in memory and written on Close, so the
+// output is no longer streamed.
+func WithCountHeader(count bool) Option {
+	return func(w *Writer) {
+		w.countHeader = count
+	}
+}
+
+// WithJSONLMeta writes a first line holding meta under the "_meta" key, e.g.
+// {"_meta":{"source":"db"}}, before the records of the NDJSON format, as
+// expected by some log ingestion pipelines. The line is written even if there
+// are no records, and precedes the line of WithCountHeader.
+func WithJSONLMeta(meta map[string]interface{}) Option {
+	return func(w *Writer) {
+		w.jsonMeta = meta
+	}
+}
+
+// WithJSONPreserveColumnOrder emits the keys of JSON objects in column order
+// rather than sorted. The JSON and NDJSON formats always write keys in
+// column order, so this only documents the intent and has no effect.
+func WithJSONPreserveColumnOrder(preserve bool) Option {
+	return func(w *Writer) {}
+}
+
+// WithJSONNumbers makes the JSONFormat emit values that are valid JSON numbers
+// as numbers. By default all values are emitted as strings, since formatters
+// return strings.
+func WithJSONNumbers(numbers bool) Option {
+	return func(w *Writer) {
+		w.jsonNumbers = numbers
+	}
+}
+
+// WithJSONColumnar writes the JSON format as a single object mapping each
+// column to the array of its values, e.g. {"name":["Bob","Sally"]}, as
+// preferred by columnar and Arrow-based loaders. All records are held in
+// memory and written on Close.
+func WithJSONColumnar(columnar bool) Option {
+	return func(w *Writer) {
+		w.jsonColumnar = columnar
+	}
+}
+
+// WithQuotedTextValues quotes Text format values that contain a colon, a
+// newline or a double quote, escaping them as Go string literals so the output
+// can be reliably re-parsed. Values containing line breaks are quoted even
+// without it unless WithRawValues is set.
+func WithQuotedTextValues(quote bool) Option {
+	return func(w *Writer) {
+		w.quoteText = quote
+	}
+}
+
+// WithChunkSize partitions the rows of each Table flush into separate tables
+// of at most n rows, each with its own header, for consumers that need
+// bounded document sizes. The JSON format is likewise written as a sequence
+// of arrays of at most n records each, one after the other, unless
+// WithJSONColumnar is set.
+func WithChunkSize(n int) Option {
+	return func(w *Writer) {
+		w.chunkSize = n
+	}
+}
+
+// WithStats collects stats for the raw values written to each column, which
+// are needed by Histogram
+func WithStats() Option {
+	return func(w *Writer) {
+		w.withStats = true
+	}
+}
+
+// WithFooterFunc computes a footer row at flush time from the number of rows
+// written and the stats of each column's raw values, accumulated across the
+// lifetime of the writer. The footer is rendered by the Table format and
+// written as a trailing row by the CSV format on every flush.
+func WithFooterFunc(fn func(rowCount int, stats map[string]Stats) []string) Option {
+	return func(w *Writer) {
+		w.footerFunc = fn
+	}
+}
+
+// WithFooter adds a footer cell to the column computed by agg from all the raw
+// values written to the column, accumulated across the lifetime of the writer
+// rather than reset when it flushes. Like WithFooterFunc, the footer is
+// rendered by the Table format and written as a trailing row by the CSV format
+// on every flush. Cells set by WithFooter take precedence over those returned
+// by the footer func.
+func WithFooter(column string, agg func(values []string) string) Option {
+	return func(w *Writer) {
+		if w.footerAggs == nil {
+			w.footerAggs = map[string]func([]string) string{}
+			w.footerValues = map[string][]string{}
+		}
+		w.footerAggs[column] = agg
+		delete(w.footerStats, column)
+	}
+}
+
+// WithAggregate adds a footer cell to the column holding agg applied to the
+// numeric values written to it, e.g. WithAggregate("size", Sum), as
+// WithFooter does. Values that don't parse as numbers are skipped. The
+// built-in aggregations are computed from the column's running stats, while
+// other funcs need every value written to the column to be kept in memory.
+func WithAggregate(column string, agg AggFunc) Option {
+	fn := statsAgg(agg)
+	if fn == nil {
+		return WithFooter(column, func(values []string) string {
+			return formatNumber(agg(parseNumbers(values)))
+		})
+	}
+	return func(w *Writer) {
+		if w.footerStats == nil {
+			w.footerStats = map[string]func(Stats) float64{}
+		}
+		w.footerStats[column] = fn
+		delete(w.footerAggs, column)
+	}
+}
+
+// WithValidateUTF8 replaces invalid UTF-8 byte sequences in every field with
+// replacement, e.g. utf8.RuneError, before the field is formatted and written
+func WithValidateUTF8(replacement rune) Option {
+	return func(w *Writer) {
+		w.validUTF8 = true
+		w.utf8Repl = replacement
+	}
+}
+
+// WithTail keeps only the last n records written in memory and emits just
+// those on Flush, like tail -n. Earlier records are discarded as new ones
+// arrive.
+func WithTail(n int) Option {
+	return func(w *Writer) {
+		w.tail = n
+	}
+}
+
+// WithDatasetColumn prepends a label column with the given name so records
+// from different logical datasets can be written through one writer with
+// WriteDataset
+func WithDatasetColumn(name string) Option {
+	return func(w *Writer) {
+		w.datasetCol = name
+		w.columns = append([]string{name}, w.columns...)
+	}
+}
+
+// WithGroupedDatasets buffers records until Flush and emits them grouped by
+// their dataset label, in the order each label was first written
+func WithGroupedDatasets() Option {
+	return func(w *Writer) {
+		w.groupSets = true
+	}
+}
+
+// WithStderrSummary writes a human readable summary of the rows, bytes and
+// errors written and the elapsed time to out when the writer is closed,
+// independent of the data output. Typically out is os.Stderr.
+func WithStderrSummary(out io.Writer) Option {
+	return func(w *Writer) {
+		w.summary = out
+	}
+}
+
+// WithSheetTypes writes a CSV header line after the column names holding a
+// Google Sheets data type hint for each column, e.g. "number" or "date", so
+// numeric columns aren't imported as text. Columns without a hint are left
+// empty.
+func WithSheetTypes(types map[string]string) Option {
+	return func(w *Writer) {
+		w.sheetTypes = types
+	}
+}
+
+// New returns a new Writer for writing. Format should be one of AllFormats or
+// a format added with Register, otherwise an error wrapping ErrUnknownFormat is reported by Error and
+// returned by every Write. The size value determines how big the internal buffer should be. When the
+// buffer fills, the writer automatically flushes it. Columns may be empty if
+// they are only known once records are read, e.g. by WriteRows.
+func New(writer io.Writer, columns []string, format string, opts ...Option) *Writer {
+	w := &Writer{
+		dest:       writer,
+		basew:      writer,
+		size:       defaultSize,
+		formatters: map[string][]Formatter{},
+		types:      map[string]ColumnType{},
+		stats:      map[string]*Stats{},
+		caches:     map[string]*lruCache{},
+		columns:    columns,
+		format:     format,
+		opts:       opts,
+		start:      time.Now(),
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	if !builtinFormat(w.format) {
+		if newFormat, ok := registeredFormat(w.format); ok {
+			w.custom = newFormat()
+		} else {
+			w.formatErr = fmt.Errorf("%w %q", ErrUnknownFormat, w.format)
+			w.err = multierror.Append(w.err, w.formatErr)
+		}
+	}
+	if w.appendMode && hasContent(w.dest) {
+		w.noHeader = true
+	}
+	if w.format == NDJSONFormat {
+		w.format = JSONFormat
+		w.jsonLines = true
+	}
+	if w.format == TSVFormat {
+		w.format = CSVFormat
+		if w.comma == 0 {
+			w.comma = '\t'
+		}
+	}
+	w.noColor = w.stripColors()
+	if w.exclude != nil {
+		w.excludeColumns()
+	}
+	if w.output != nil {
+		w.projectColumns()
+	}
+	for index := range w.formattersAt {
+		if index < 0 || index >= len(w.columns) {
+			w.err = multierror.Append(w.err, fmt.Errorf("formatter index %d out of range for %d columns", index, len(w.columns)))
+			delete(w.formattersAt, index)
+		}
+	}
+	w.bindOutput(w.dest)
+	w.table = w.newTable()
+	w.csvw = csv.NewWriter(w.basew)
+	if w.comma != 0 {
+		w.csvw.Comma = w.comma
+	}
+	w.csvw.UseCRLF = w.crlf
+	w.strw = bufio.NewWriterSize(w.basew, w.size)
+	if w.alignCSV {
+		w.csvQuoting = true
+		if w.rawRecSep == "" {
+			w.rawRecSep = w.csvRecordSep()
+		}
+	}
+	if w.rfc4180 {
+		w.rawSep = string(w.csvw.Comma)
+		w.rawRecSep = "\r\n"
+		w.rawEscaper = rfc4180Quoter(w.rawSep)
+	}
+	if w.csvQuoting {
+		if w.rawSep == "" {
+			w.rawSep = string(w.csvw.Comma)
+		}
+		w.rawEscaper = csvQuoter(w.rawSep, w.rawRecSep)
+	}
+	if w.csvEscape != 0 {
+		if w.rawSep == "" {
+			w.rawSep = string(w.csvw.Comma)
+		}
+		if w.rawRecSep == "" {
+			w.rawRecSep = w.csvRecordSep()
+		}
+		w.rawEscaper = charEscaper(string(w.csvEscape), w.rawSep, w.rawRecSep, "\x00")
+	}
+	if w.rawSep != "" && w.rawEscaper == nil {
+		w.rawEscaper = charEscaper(`\`, w.rawSep, w.rawRecSep)
+	}
+	if w.headerState == nil && !w.dynamic && len(w.columns) > 0 {
+		w.writeHeader()
+	}
+	return w
+}
+
+// bindOutput sets the writer's output to out, wrapped in the byte counter,
+// pipe and envelope set by options
+func (w *Writer) bindOutput(out io.Writer) {
+	w.dest, w.basew = out, out
+	if w.summary != nil {
+		w.counter = &countingWriter{w: w.basew}
+		w.basew = w.counter
+	}
+	if w.pipe != nil {
+		w.pipew = w.pipe(w.basew)
+		w.basew = w.pipew
+	}
+	if w.envelope != nil {
+		w.envelope = &envelopeWriter{w: w.basew, prefix: w.envelope.prefix, suffix: w.envelope.suffix}
+		w.basew = w.envelope
+	}
+}
+
+// headerLines returns the column names followed by any CSV header lines
+// describing the columns
+func (w *Writer) headerLines() [][]string {
+	header := [][]string{w.headerNames()}
+	if w.typeHeader {
+		header = append(header, w.columnTypes())
+	}
+	if w.sheetTypes != nil {
+		hints := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			hints[i] = w.sheetTypes[col]
+		}
+		header = append(header, hints)
+	}
+	return header
+}
+
+// headerNames returns a new slice of the column names to display in headers,
+// with the labels set by WithHeaderLabels applied
+func (w *Writer) headerNames() []string {
+	names := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		if label, ok := w.headerLabels[col]; ok {
+			col = label
+		}
+		names[i] = col
+	}
+	return names
+}
+
+// writeHeader writes the header for the format, or to the header sidecar if
+// one is set
+func (w *Writer) writeHeader() {
+	if w.pivoting() {
+		return
+	}
+	if w.noHeader {
+		// a GFM table can't do without its header and delimiter rows, so
+		// the header is left blank instead, unless appending to a table
+		if w.format == MarkdownFormat && !w.appendMode {
+			w.writeMarkdownHeader(make([]string, len(w.columns)))
+		}
+		return
+	}
+	if w.textHeader && w.format == TextFormat {
+		if _, err := w.strw.WriteString(w.renderTextHeader()); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing header to text: %s", err))
+		}
+	}
+	if w.format == HTMLFormat {
+		w.renderHTMLHeader(&w.str)
+		w.htmlOpen = true
+		if _, err := w.strw.WriteString(w.str.String()); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing header to html: %s", err))
+		}
+		w.str.Reset()
+	}
+	if w.custom != nil {
+		if err := w.custom.WriteHeader(w.strw, w.headerNames()); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing header to %s: %s", w.format, err))
+		}
+	}
+	if w.format == MarkdownFormat {
+		w.writeMarkdownHeader(w.headerNames())
+	}
+	header := w.headerLines()
+	if w.sidecar != nil {
+		for _, line := range header {
+			if _, err := io.WriteString(w.sidecar, w.renderCSV(line)); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error writing header sidecar: %s", err))
+				return
+			}
+		}
+		return
+	}
+	w.table.SetHeader(w.headerNames())
+	w.colorTable()
+	if w.alignCSV {
+		w.headerPending = true
+		return
+	}
+	if w.format != CSVFormat {
+		return
+	}
+	for _, line := range header {
+		w.writeCSV(line)
+	}
+}
+
+// NewForFile returns a new Writer for f in the format implied by the extension
+// of its name, see FormatForExtension
+func NewForFile(f *os.File, columns []string, opts ...Option) (*Writer, error) {
+	format, err := FormatForExtension(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return New(f, columns, format, opts...), nil
+}
+
+// NewFile creates or truncates the file at path and returns a new Writer for
+// it, along with a close function that flushes the writer and closes the
+// file. The close function returns both write and file errors.
+func NewFile(path string, columns []string, format string, opts ...Option) (*Writer, func() error, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating file: %s", err)
+	}
+	w := New(f, columns, format, append(opts[:len(opts):len(opts)], WithCloseUnderlying())...)
+	w.opts = opts
+	return w, w.Close, nil
+}
+
+// NewAppendFile opens the file at path for appending, creating it if needed,
+// and returns a new Writer for it with WithAppend, so the header is only
+// written to a new or empty file. The close function is the same as for
+// NewFile.
+func NewAppendFile(path string, columns []string, format string, opts ...Option) (*Writer, func() error, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening file: %s", err)
+	}
+	w := New(f, columns, format, append(opts[:len(opts):len(opts)], WithAppend(), WithCloseUnderlying())...)
+	w.opts = opts
+	return w, w.Close, nil
+}
+
+// hasContent returns whether the output reports a non-zero size through its
+// Stat method
+func hasContent(out io.Writer) bool {
+	s, ok := out.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+	info, err := s.Stat()
+	return err == nil && info.Size() > 0
+}
+
+// NewFunc returns a new Writer that, instead of writing to an io.Writer,
+// invokes fn with the bytes rendered since the previous flush every time the
+// writer is flushed. This allows for custom sinks such as message queues or
+// batched API calls. Errors returned by fn are aggregated into Error.
+func NewFunc(fn func(rendered []byte) error, columns []string, format string, opts ...Option) *Writer {
+	sink := &funcWriter{fn: fn}
+	w := New(sink, columns, format, opts...)
+	w.sink = sink
+	return w
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes p to the underlying writer and counts the bytes written
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// envelopeWriter writes a prefix before the first write and a suffix when
+// closed
+type envelopeWriter struct {
+	w       io.Writer
+	prefix  []byte
+	suffix  []byte
+	started bool
+	closed  bool
+}
+
+// start writes the prefix if it has not been written yet
+func (ew *envelopeWriter) start() error {
+	if ew.started {
+		return nil
+	}
+	ew.started = true
+	_, err := ew.w.Write(ew.prefix)
+	return err
+}
+
+// Write writes p after the prefix
+func (ew *envelopeWriter) Write(p []byte) (int, error) {
+	if err := ew.start(); err != nil {
+		return 0, err
+	}
+	return ew.w.Write(p)
+}
+
+// close writes the suffix, preceded by the prefix if nothing was written
+func (ew *envelopeWriter) close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	if err := ew.start(); err != nil {
+		return err
+	}
+	_, err := ew.w.Write(ew.suffix)
+	return err
+}
+
+// funcWriter buffers writes in memory and hands them to a callback on Flush
+type funcWriter struct {
+	fn  func([]byte) error
+	buf bytes.Buffer
+}
+
+// Write appends p to the pending buffer
+func (fw *funcWriter) Write(p []byte) (int, error) {
+	return fw.buf.Write(p)
+}
+
+// Flush invokes the callback with a copy of the pending buffer
+func (fw *funcWriter) Flush() error {
+	if fw.buf.Len() == 0 {
+		return nil
+	}
+	rendered := make([]byte, fw.buf.Len())
+	copy(rendered, fw.buf.Bytes())
+	fw.buf.Reset()
+	return fw.fn(rendered)
+}
+
+// Write writes the record to the internal buffer
+func (w *Writer) Write(record []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(record)
+}
+
+// write writes the record to the internal buffer while holding the lock
+func (w *Writer) write(record []string) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.formatErr != nil {
+		return w.formatErr
+	}
+	if w.aborted != nil {
+		return w.aborted
+	}
+	if w.pageFull() {
+		if err := w.nextPage(); err != nil {
+			return err
+		}
+	}
+	record, err := w.projectRecord(record)
+	if err == nil {
+		err = w.checkLength(record)
+	}
+	if err == nil && w.transformers != nil {
+		var keep bool
+		if record, keep = w.transformRecord(record); !keep {
+			return nil
+		}
+		err = w.checkLength(record)
+	}
+	if err == nil && w.rfc4180 {
+		err = checkNUL(record)
+	}
+	if err != nil {
+		index := w.index
+		w.index++
+		return w.recordError(index, record, err)
+	}
+	var extra appendedValues
+	if w.rowHash != nil {
+		extra.hash = w.rowHash.sum(record)
+	}
+	if w.baseline != nil {
+		change, emit := w.baselineChange(record)
+		if !emit {
+			return nil
+		}
+		extra.change = change
+	}
+	if w.reduce != nil {
+		w.reduceRecord(w.appendValues(record, extra))
+		if !w.keepReduced {
+			return nil
+		}
+	}
+	if w.run != nil {
+		err = w.countRun(record, extra)
+	} else {
+		err = w.writeRecord(w.appendValues(record, extra))
+	}
+	if err == nil && w.paging != nil {
+		w.paging.rows++
+	}
+	return err
+}
+
+// writeRecord formats a complete record and writes it to the internal buffer
+func (w *Writer) writeRecord(record []string) error {
+	var values []string
+	var err error
+	if w.reuseValues() {
+		w.scratch = w.formatRecordTo(w.scratch[:0], record)
+		values, err = w.encodeRecord(w.scratch)
+	} else {
+		values, err = w.encodeRecord(w.formatRecord(record))
+	}
+	if err != nil {
+		index := w.index
+		w.index++
+		return w.recordError(index, record, err)
+	}
+	r := row{index: w.index, raw: record, values: values}
+	w.index++
+	if w.collectingStats() {
+		w.collectStats(record)
+	}
+	if w.buffering() {
+		w.rows = append(w.rows, r)
+		if w.tail > 0 && len(w.rows) > w.tail {
+			w.rows = w.rows[len(w.rows)-w.tail:]
+			return nil
+		}
+		w.countPending(r.values)
+		if w.bufferFull() && w.flushDue() {
+			w.flush()
+		}
+		return nil
+	}
+	if err := w.writeRow(r); err != nil {
+		return err
+	}
+	w.countPending(r.values)
+	switch {
+	case !w.flushDue():
+	case w.format == TableFormat && !w.transpose && w.stream == nil:
+		// rendering part of a Table would size its columns apart from the
+		// rest, so it is only rendered whole, once the limits set by
+		// WithMaxBufferedRows or WithMaxBufferedBytes are reached
+		if w.bufferFull() {
+			w.flush()
+		}
+	case w.pending >= w.size || w.bufferFull():
+		w.autoFlush()
+	}
+	return nil
+}
+
+// WriteFlush writes the record and immediately flushes it to the output, for
+// streaming where every record must be visible as soon as it is written. A
+// Table is rendered a row at a time: the header is written with the first
+// record and the bottom border and footer on the next Flush. Records held in
+// memory by options such as WithStableOutput are still only written on Flush.
+func (w *Writer) WriteFlush(record []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(record); err != nil {
+		return err
+	}
+	w.autoFlush()
+	return nil
+}
+
+// WriteAll writes each record in sequence as Write would, stopping at and
+// returning the first error. Records before the failing one are still written.
+func (w *Writer) WriteAll(records [][]string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, record := range records {
+		if err := w.write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// excludeColumns sets the output columns to the output columns, or all
+// columns if unset, without the excluded ones
+func (w *Writer) excludeColumns() {
+	output := w.output
+	if output == nil {
+		output = w.columns
+	}
+	excluded := map[string]bool{}
+	for _, col := range w.exclude {
+		if w.columnIndex(col) < 0 {
+			w.err = multierror.Append(w.err, fmt.Errorf("unknown excluded column %q", col))
+			continue
+		}
+		excluded[col] = true
+	}
+	w.output = []string{}
+	for _, col := range output {
+		if !excluded[col] {
+			w.output = append(w.output, col)
+		}
+	}
+}
+
+// projectColumns resolves the output columns to their indices in the input
+// records and replaces the columns with them
+func (w *Writer) projectColumns() {
+	var columns []string
+	for _, col := range w.output {
+		i := w.columnIndex(col)
+		if i < 0 {
+			w.err = multierror.Append(w.err, fmt.Errorf("unknown output column %q", col))
+			continue
+		}
+		columns = append(columns, col)
+		w.project = append(w.project, i)
+	}
+	w.inputColumns = w.columns
+	w.columns = columns
+}
+
+// projectRecord maps a full input record to the output columns, if the writer
+// is configured with WithColumns
+func (w *Writer) projectRecord(record []string) ([]string, error) {
+	if w.output == nil {
+		return record, nil
+	}
+	if len(record) != len(w.inputColumns) {
+		return record, fmt.Errorf("%w: record has %d fields, expected %d", ErrRecordLengthMismatch, len(record), len(w.inputColumns))
+	}
+	projected := make([]string, len(w.project))
+	for i, j := range w.project {
+		projected[i] = record[j]
+	}
+	return projected, nil
+}
+
+// transformRecord runs the row transformers on the record, stopping as soon as
+// one drops it
+func (w *Writer) transformRecord(record []string) ([]string, bool) {
+	for _, transform := range w.transformers {
+		var keep bool
+		if record, keep = transform(record); !keep {
+			return nil, false
+		}
+	}
+	return record, true
+}
+
+// checkLength returns an error if the record doesn't have a field for every
+// column written by the caller
+func (w *Writer) checkLength(record []string) error {
+	if expected := len(w.columns) - w.appendedColumns(); len(record) != expected {
+		return fmt.Errorf("%w: record has %d fields, expected %d", ErrRecordLengthMismatch, len(record), expected)
+	}
+	return nil
+}
+
+// appendedColumn is a column the writer appends to the records written by
+// the caller
+type appendedColumn int
+
+const (
+	changeColumn appendedColumn = iota
+	hashColumn
+	countColumn
+)
+
+// appendedValues holds the values of the appended columns of a record
+type appendedValues struct {
+	change, hash, count string
+}
+
+// appendedColumns returns the number of columns the writer appends to the
+// records written by the caller
+func (w *Writer) appendedColumns() int {
+	return len(w.appended)
+}
+
+// appendValues returns a copy of record with the values of the appended
+// columns, in the order in which their options added them to the header. The
+// run length count is only appended once a run is finished.
+func (w *Writer) appendValues(record []string, extra appendedValues) []string {
+	if len(w.appended) == 0 {
+		return record
+	}
+	out := make([]string, len(record), len(record)+len(w.appended))
+	copy(out, record)
+	for _, c := range w.appended {
+		switch c {
+		case changeColumn:
+			out = append(out, extra.change)
+		case hashColumn:
+			out = append(out, extra.hash)
+		case countColumn:
+			if extra.count != "" {
+				out = append(out, extra.count)
+			}
+		}
+	}
+	return out
+}
+
+// recordColumns returns the columns of the records written by the caller,
+// before projection and without the columns the writer appends itself
+func (w *Writer) recordColumns() []string {
+	if w.output != nil {
+		return w.inputColumns
+	}
+	return w.columns[:len(w.columns)-w.appendedColumns()]
+}
+
+// WriteDataset writes the record tagged with the dataset label. The writer must
+// be configured with WithDatasetColumn.
+func (w *Writer) WriteDataset(label string, record []string) error {
+	if w.datasetCol == "" {
+		return fmt.Errorf("no dataset column configured")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(append([]string{label}, record...))
+}
+
+// WriteSeparator writes a visual separator between records to group them
+// manually: a blank line in CSV, an empty row in Table and a bare --- line in
+// Text. Separators are kept in place among buffered records but are
+// meaningless once records are sorted.
+func (w *Writer) WriteSeparator() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.aborted != nil {
+		return w.aborted
+	}
+	if w.buffering() {
+		w.rows = append(w.rows, row{separator: true})
+		return nil
+	}
+	return w.writeSeparator()
+}
+
+// writeSeparator writes a separator to the format writer
+func (w *Writer) writeSeparator() error {
+	switch w.format {
+	case CSVFormat:
+		if err := w.writeCSV(nil); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing separator to csv: %s", err))
+			return err
+		}
+	case TableFormat:
+		if w.transpose {
+			break
+		}
+		if w.stream != nil {
+			if err := w.writeStreamRow(make([]string, len(w.columns)), true); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error writing separator to table: %s", err))
+				return err
+			}
+			break
+		}
+		w.table.Append(make([]string, len(w.columns)))
+	case TextFormat:
+		if _, err := w.strw.WriteString("---\n"); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing separator to text: %s", err))
+			return err
+		}
+	case PromFormat, LogfmtFormat:
+		if _, err := w.strw.WriteString("\n"); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing separator to %s: %s", w.format, err))
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRow writes a formatted row to the format writer
+func (w *Writer) writeRow(r row) error {
+	if r.separator {
+		return w.writeSeparator()
+	}
+	if r.section {
+		if err := w.writeSection(r.values); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing section to %s: %s", w.format, err))
+			return err
+		}
+		return nil
+	}
+	switch w.format {
+	case CSVFormat:
+		if err := w.writeCSVRecord(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to csv: %s", err))
+		}
+	case TableFormat:
+		if w.transpose {
+			if err := w.writeTransposed(r.values); err != nil {
+				return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to table: %s", err))
+			}
+			break
+		}
+		if w.stream != nil {
+			if err := w.writeStreamRow(r.values, false); err != nil {
+				return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to table: %s", err))
+			}
+			break
+		}
+		if w.headerDue() {
+			header := w.headerNames()
+			for i, col := range header {
+				header[i] = tablewriter.Title(col)
+			}
+			w.table.Append(header)
+		}
+		w.table.Append(w.tableValues(r.values))
+	case PromFormat:
+		if err := w.writeProm(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to prom: %s", err))
+		}
+	case LogfmtFormat:
+		if err := w.writeLogfmt(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to logfmt: %s", err))
+		}
+	case MermaidFormat:
+		if err := w.writeMermaid(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to mermaid: %s", err))
+		}
+	case JSONFormat:
+		if err := w.writeJSON(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to json: %s", err))
+		}
+	case PGCopyFormat:
+		if err := w.writePGCopy(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to pgcopy: %s", err))
+		}
+	case MarkdownFormat:
+		if err := w.writeMarkdown(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to markdown: %s", err))
+		}
+	case YAMLFormat:
+		if err := w.writeYAML(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to yaml: %s", err))
+		}
+	case HTMLFormat:
+		if err := w.writeHTML(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to html: %s", err))
+		}
+	case TextFormat:
+		if err := w.writeText(r); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to text: %s", err))
+		}
+	default:
+		if w.custom == nil {
+			break
+		}
+		if err := w.custom.WriteRow(w.strw, r.values); err != nil {
+			return w.recordError(r.index, r.raw, fmt.Errorf("error writing record to %s: %s", w.format, err))
+		}
+	}
+	return nil
+}
+
+// writeText writes the row as a Text format block, preceded by the header if
+// it is due to be repeated
+func (w *Writer) writeText(r row) error {
+	if w.textTemplate == nil {
+		// render straight into the output buffer, since a block can't fail
+		// halfway other than by failing to write
+		if w.headerDue() {
+			if _, err := w.strw.WriteString(w.renderTextHeader()); err != nil {
+				return err
+			}
+		}
+		return w.renderText(w.strw, r.values)
+	}
+	if w.headerDue() {
+		w.str.WriteString(w.renderTextHeader())
+	}
+	if err := w.renderTextTemplate(&w.str, r.values); err != nil {
+		w.str.Reset()
+		return err
+	}
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderText renders values into b as a Text format block
+func (w *Writer) renderText(b io.StringWriter, values []string) error {
+	if _, err := b.WriteString("---\n"); err != nil {
+		return err
+	}
+	for i, v := range values {
+		if w.quoteTextValue(v) {
+			v = strconv.Quote(v)
+		}
+		for _, s := range [...]string{w.columns[i], ": ", v, "\n"} {
+			if _, err := b.WriteString(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSize returns the number of bytes the record occupies when rendered in
+// the configured format, without writing it. For formats that render records
+// together, such as Table and Mermaid, this is the size of the standalone
+// rendering returned by Render, which only approximates the record's share of
+// the full output since borders and column widths depend on the other rows.
+func (w *Writer) RecordSize(record []string) (int, error) {
+	rendered, err := w.Render(record)
+	if err != nil {
+		return 0, err
+	}
+	return len(rendered), nil
+}
+
+// Render formats and renders a single record in the configured format and
+// returns the rendered bytes, without a header and without touching the
+// writer's buffers or output. The Table format renders a standalone
+// single-row table.
+func (w *Writer) Render(record []string) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	record, err := w.projectRecord(record)
+	if err != nil {
+		return nil, err
+	}
+	if len(record) != len(w.columns) {
+		return nil, fmt.Errorf("%w: record has %d fields, expected %d", ErrRecordLengthMismatch, len(record), len(w.columns))
+	}
+	values, err := w.encodeRecord(w.formatRecord(record))
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	switch w.format {
+	case CSVFormat:
+		b.WriteString(w.renderCSV(values))
+	case TableFormat:
+		if w.transpose {
+			w.renderTransposed(&b, values)
+			break
+		}
+		table := tablewriter.NewWriter(&b)
+		w.styleTable(table)
+		if aligns, ok := w.tableAlignments(); ok {
+			table.SetColumnAlignment(aligns)
+		}
+		table.Append(w.tableValues(values))
+		table.Render()
+	case TextFormat:
+		if w.textTemplate != nil {
+			if err := w.renderTextTemplate(&b, values); err != nil {
+				return nil, err
+			}
+			break
+		}
+		w.renderText(&b, values)
+	case PromFormat:
+		if err := w.renderProm(&b, values); err != nil {
+			return nil, err
+		}
+	case LogfmtFormat:
+		w.renderLogfmt(&b, values)
+	case JSONFormat:
+		w.renderJSON(&b, values)
+		b.WriteString("\n")
+	case PGCopyFormat:
+		w.renderPGCopy(&b, record, values)
+	case MarkdownFormat:
+		renderMarkdownRow(&b, values)
+	case YAMLFormat:
+		w.renderYAML(&b, values)
+	case HTMLFormat:
+		if err := w.renderHTMLRow(&b, w.index, values); err != nil {
+			return nil, err
+		}
+	case MermaidFormat:
+		w.renderMermaidOpen(&b)
+		renderMermaidBlocks(&b, "r0", values)
+		b.WriteString("```\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// headerDue counts a row and returns whether a repeated header should
+// precede it
+func (w *Writer) headerDue() bool {
+	if w.repeatHeader <= 0 || w.noHeader {
+		return false
+	}
+	due := w.sinceHeader == w.repeatHeader
+	if due {
+		w.sinceHeader = 0
+	}
+	w.sinceHeader++
+	return due
+}
+
+// renderTextHeader renders the column names as a header line for the Text
+// format, along with their types if any are declared
+func (w *Writer) renderTextHeader() string {
+	if len(w.types) == 0 {
+		return fmt.Sprintf("columns: %s\n", strings.Join(w.headerNames(), ", "))
+	}
+	types := w.columnTypes()
+	columns := w.headerNames()
+	for i, col := range columns {
+		columns[i] = fmt.Sprintf("%s (%s)", col, types[i])
+	}
+	return fmt.Sprintf("columns: %s\n", strings.Join(columns, ", "))
+}
+
+// buffering returns whether records must be held in memory until Flush
+func (w *Writer) buffering() bool {
+	return w.stable || w.sort != nil || w.tail > 0 || w.groupSets || w.grouping() || w.merge != nil ||
+		(w.jsonColumnar && w.format == JSONFormat) || w.countingLines() || w.format == XLSXFormat ||
+		(w.chunkSize > 0 && w.format == TableFormat) ||
+		(w.subtotals != nil && w.format == TableFormat) ||
+		(w.alignCSV && w.format == CSVFormat) || w.pivoting() ||
+		(w.headerState != nil && !w.headerDecided) || (w.dynamic && !w.columnsFixed)
+}
+
+// flushRows writes all rows held in memory to the format writer
+func (w *Writer) flushRows() {
+	rows := w.rows
+	w.rows = nil
+	if w.merge != nil {
+		rows = w.mergeDuplicates(rows)
+	}
+	if w.stable {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return lessValues(rows[i].values, rows[j].values)
+		})
+	}
+	if w.sort != nil {
+		w.sortRows(rows)
+	}
+	if w.groupSets {
+		groupDatasets(rows)
+	}
+	if w.grouping() {
+		rows = w.groupRows(rows)
+	}
+	if w.pivoting() {
+		w.writePivot(rows)
+		return
+	}
+	if w.subtotals != nil && w.format == TableFormat {
+		rows, w.total = w.insertSubtotals(rows)
+	}
+	if w.alignCSV && w.format == CSVFormat {
+		w.writeAlignedCSV(rows)
+		return
+	}
+	if w.jsonColumnar && w.format == JSONFormat {
+		if !w.closed {
+			w.rows = rows
+			return
+		}
+		w.writeJSONColumnar(rows)
+		return
+	}
+	if w.format == XLSXFormat {
+		if !w.closed {
+			w.rows = rows
+			return
+		}
+		if err := w.writeXLSX(rows); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing xlsx: %s", err))
+		}
+		return
+	}
+	if w.countingLines() {
+		if !w.closed {
+			w.rows = rows
+			return
+		}
+		if err := w.writeCountHeader(rows); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing count header to json: %s", err))
+		}
+	}
+	for i, r := range rows {
+		if w.chunkSize > 0 && i > 0 && i%w.chunkSize == 0 {
+			w.renderTable(true)
+		}
+		if err := w.writeRow(r); err != nil && w.aborted != nil {
+			return
+		}
+	}
+}
+
+// renderTable renders the rows appended to the table since it was last
+// rendered. If the table is still open from an earlier partial render, its top
+// border and header are left out, and unless final is set the bottom border is
+// left out so later rows can continue the table.
+func (w *Writer) renderTable(final bool) {
+	if w.format != TableFormat || w.stream != nil || w.transpose {
+		return
+	}
+	var buf bytes.Buffer
+	w.table.SetOutput(&buf)
+	w.table.Render()
+	w.table.SetOutput(w.basew)
+	w.table.ClearRows()
+	out := buf.String()
+	if w.tableOpen {
+		out = trimTableHeader(out, w.tableBorders())
+	}
+	if !final && !w.tableStyle.noBorder {
+		out = strings.TrimSuffix(out, "\n")
+		out = out[:strings.LastIndex(out, "\n")+1]
+	}
+	if _, err := io.WriteString(w.basew, out); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error writing table: %s", err))
+	}
+	w.tableOpen, w.tableDone = !final, final
+	if final {
+		w.sinceHeader = 0
+	}
+}
+
+// trimTableHeader removes the top border and the header from a rendered
+// table, whichever it has, which end at the given number of border lines
+func trimTableHeader(out string, last int) string {
+	if last == 0 {
+		return out
+	}
+	borders := 0
+	for i := 0; i < len(out); {
+		end := strings.IndexByte(out[i:], '\n')
+		if end < 0 {
+			break
+		}
+		if out[i] == '+' {
+			borders++
+			if borders == last {
+				return out[i+end+1:]
+			}
+		}
+		i += end + 1
+	}
+	return out
+}
+
+// flushBuffer flushes the CSV or text buffer to the output writer
+func (w *Writer) flushBuffer() {
+	var err error
+	switch w.format {
+	case TableFormat:
+		if !w.transpose {
+			return
+		}
+		err = w.strw.Flush()
+	case CSVFormat:
+		if w.rawSep != "" {
+			err = w.strw.Flush()
+			break
+		}
+		w.csvw.Flush()
+		err = w.csvw.Error()
+	default:
+		err = w.strw.Flush()
+	}
+	if err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error flushing %s: %s", w.format, err))
+	}
+}
+
+// Flush flushes all records from the internal buffer to its output writer
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.flush()
+}
+
+// flush flushes all records from the internal buffer while holding the lock
+func (w *Writer) flush() {
+	w.endRun()
+	w.writeReduced()
+	w.decideSharedHeader()
+	w.fixColumns()
+	w.flushRows()
+	footer := w.footer()
+	switch w.format {
+	case CSVFormat:
+		// aligned CSV pads the footer along with the rows
+		if footer != nil && (!w.alignCSV || w.pivoting()) {
+			if err := w.writeCSV(footer); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error writing footer to csv: %s", err))
+			}
+		}
+		w.flushBuffer()
+	case TextFormat, PromFormat, LogfmtFormat, MermaidFormat, JSONFormat, PGCopyFormat, MarkdownFormat, YAMLFormat, HTMLFormat:
+		w.closeMermaid()
+		w.flushBuffer()
+		w.strw.Reset(w.basew)
+		w.str.Reset()
+	case TableFormat:
+		if footer == nil {
+			footer = w.total
+		}
+		if w.transpose {
+			w.flushBuffer()
+			break
+		}
+		if w.stream != nil {
+			if err := w.closeStream(footer); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error writing table: %s", err))
+			}
+			if err := w.strw.Flush(); err != nil {
+				w.err = multierror.Append(w.err, fmt.Errorf("error flushing table: %s", err))
+			}
+			break
+		}
+		if w.tableDone && w.table.NumLines() == 0 {
+			// the rows were already rendered, e.g. by WithMaxBufferedRows,
+			// so there's no need for another, empty table
+			break
+		}
+		if footer != nil {
+			w.table.SetFooter(footer)
+		}
+		w.renderTable(true)
+		w.table.ClearFooter()
+	default:
+		if w.custom == nil {
+			break
+		}
+		if err := w.custom.Flush(w.strw); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error flushing %s: %s", w.format, err))
+		}
+		w.flushBuffer()
+	}
+	w.pending = 0
+	w.pendingRows = 0
+	w.lastFlush = time.Now()
+	w.flushOutput()
+}
+
+// flushOutput flushes the output pipe and func sink, if any
+func (w *Writer) flushOutput() {
+	if f, ok := w.pipew.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error flushing output pipe: %s", err))
+		}
+	}
+	if w.sink != nil {
+		if err := w.sink.Flush(); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error flushing to func: %s", err))
+		}
+	}
+}
+
+// WriteTrailer finishes the output as Close would, e.g. with the footer and
+// the closing of a JSON array, and then appends p verbatim to the underlying
+// writer. This is an escape hatch for output that is nested inside a larger
+// document, e.g. a custom footer or a closing tag, so no records can be
+// written afterwards. Close must still be called to finish an envelope or a
+// pipe and to close the output. Once the writer is closed, p is only
+// appended.
+func (w *Writer) WriteTrailer(p []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		w.finish()
+	}
+	if _, err := w.basew.Write(p); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error writing trailer: %s", err))
+		return err
+	}
+	w.flushOutput()
+	return nil
+}
+
+// Options returns the options the writer was configured with, so that a new
+// writer with an equivalent configuration can be created from them. Options
+// added internally by constructors such as NewFile are not included.
+// Runtime state such as buffered records and errors is not included.
+func (w *Writer) Options() []Option {
+	opts := make([]Option, len(w.opts))
+	copy(opts, w.opts)
+	return opts
+}
+
+// Sync flushes the writer and, if the output is an *os.File, commits its
+// contents to stable storage with File.Sync
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	f, ok := w.dest.(*os.File)
+	if !ok {
+		return nil
+	}
+	if err := f.Sync(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error syncing file: %s", err))
+		return err
+	}
+	return nil
+}
+
+// Close flushes the writer, writes any closing bytes such as the end of a JSON
+// array, closes the output pipe if it is an io.Closer, and the output itself
+// with WithCloseUnderlying, and returns any error encountered while writing.
+// Writes after Close fail with ErrClosed and further calls to Close only
+// return the error.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return w.err
+	}
+	// a writer left closed by a failed page change already finished its page
+	pageDone := w.closed
+	w.closed, w.done = true, true
+	w.close()
+	if w.paging != nil && !pageDone {
+		w.finishPage()
+	}
+	if w.closeDest {
+		w.closeOutput()
+	}
+	if w.summary != nil {
+		w.writeSummary()
+	}
+	return w.err
+}
+
+// close flushes and finishes the output while holding the lock
+func (w *Writer) close() {
+	w.finish()
+	if w.envelope != nil {
+		if err := w.envelope.close(); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error writing envelope suffix: %s", err))
+		}
+		w.flushOutput()
+	}
+	if c, ok := w.pipew.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			w.err = multierror.Append(w.err, fmt.Errorf("error closing output pipe: %s", err))
+		}
+	}
+}
+
+// finish flushes the records and finishes the format's output, e.g. with the
+// footer and the closing of a JSON array, unless it is already finished
+func (w *Writer) finish() {
+	if w.finished {
+		return
+	}
+	w.finished = true
+	w.flush()
+	if err := w.closeJSON(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error closing json: %s", err))
+	}
+	if err := w.closeHTML(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error closing html: %s", err))
+	}
+	w.flushOutput()
+}
+
+// writeSummary writes the summary of what was written to the summary writer
+func (w *Writer) writeSummary() {
+	errs := 0
+	if merr, ok := w.err.(*multierror.Error); ok {
+		errs = len(merr.Errors)
+	}
+	elapsed := time.Since(w.start).Round(time.Millisecond)
+	fmt.Fprintf(w.summary, "wrote %d rows (%d bytes) in %s with %d errors\n", w.written(), w.counter.n, elapsed, errs)
+}
+
+// written returns the number of records written without an error, leaving
+// out those that failed or were skipped by the WithOnError func
+func (w *Writer) written() int {
+	return w.index - w.failed
+}
+
+// Error returns whether there was an error writing.
+func (w *Writer) Error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// RecordError is the failure to write a record, identified by its index among
+// the records written since the writer was created or its buffers were reset
+type RecordError struct {
+	Index  int
+	Record []string
+	Err    error
+}
+
+// Error returns the error message prefixed with the record index
+func (re RecordError) Error() string {
+	return fmt.Sprintf("record %d: %s", re.Index, re.Err)
+}
+
+// Unwrap returns the underlying error
+func (re RecordError) Unwrap() error {
+	return re.Err
+}
+
+// Errors returns the failures to write individual records, in the order they
+// occurred. Failures skipped by the WithOnError callback are not included.
+func (w *Writer) Errors() []RecordError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]RecordError(nil), w.recordErrs...)
+}
+
+// recordError reports a failure to write the record at index, either to the
+// WithOnError callback or by aggregating it into the writer's error as a
+// RecordError
+func (w *Writer) recordError(index int, record []string, err error) error {
+	w.failed++
+	if w.onError != nil && w.onError(index, record, err) {
+		return nil
+	}
+	re := RecordError{Index: index, Record: append([]string(nil), record...), Err: err}
+	w.recordErrs = append(w.recordErrs, re)
+	if w.onError == nil {
+		w.err = multierror.Append(w.err, re)
+		return re
+	}
+	w.aborted = fmt.Errorf("writing aborted at record %d: %w", index, err)
+	w.err = multierror.Append(w.err, w.aborted)
+	return w.aborted
+}
+
+// setColumns replaces the writer's columns, failing if they are locked and
+// would change
+func (w *Writer) setColumns(columns []string) error {
+	if w.lockCols && !equalColumns(w.columns, columns) {
+		err := fmt.Errorf("%w: cannot change %v to %v", ErrColumnsLocked, w.columns, columns)
+		w.err = multierror.Append(w.err, err)
+		return err
+	}
+	w.columns = columns
+	return nil
+}
+
+// equalColumns returns whether a and b contain the same columns in order
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// columnIndex returns the index of the named column, or -1 if there is none
+func (w *Writer) columnIndex(name string) int {
+	for i, col := range w.columns {
+		if col == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnTypes returns the declared type of each column in column order
+func (w *Writer) columnTypes() []string {
+	types := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		t, ok := w.types[col]
+		if !ok {
+			t = String
+		}
+		types[i] = string(t)
+	}
+	return types
+}
+
+// formatRecord applies column formatters to column values
+func (w *Writer) formatRecord(record []string) []string {
+	return w.formatRecordTo(make([]string, 0, len(record)), record)
+}
+
+// formatRecordTo appends the formatted values of record to dst
+func (w *Writer) formatRecordTo(dst, record []string) []string {
+	for i, val := range record {
+		dst = append(dst, w.formatValue(i, val, record))
+	}
+	return dst
+}
+
+// reuseValues returns whether the formatted values of a record are written
+// straight to the output without being kept, so the same slice can be reused
+// for every record
+func (w *Writer) reuseValues() bool {
+	return (w.format == TextFormat || w.format == CSVFormat) && !w.buffering()
+}
+
+// formatValue applies the formatters of the column at index i to val, a value
+// of record
+func (w *Writer) formatValue(i int, val string, record []string) string {
+	colName := w.columns[i]
+	if w.validUTF8 {
+		val = strings.ToValidUTF8(val, string(w.utf8Repl))
+	}
+	if w.stripANSI {
+		val = ansiEscape.ReplaceAllString(val, "")
+	}
+	if w.trimFields {
+		val = strings.TrimSpace(val)
+	}
+	val = w.applyFormatters(colName, val, record)
+	for _, formatter := range w.formattersAt[i] {
+		val = w.applyFormatter(formatter, colName, val, record)
+	}
+	if w.noColor && strings.IndexByte(val, '\x1b') >= 0 {
+		val = ansiEscape.ReplaceAllString(val, "")
+	}
+	if width, ok := w.maxWidths[colName]; ok {
+		val = limitWidth(val, width, w.truncate)
+	}
+	if w.newline != "" {
+		val = normalizeNewlines(val, w.newline)
+	}
+	if val == "" {
+		val = w.emptyValue
+	}
+	return val
+}
+
+// applyFormatters runs the column's formatter chain on val, consulting the
+// column's formatter cache if it has one. The cache is bypassed for chains with
+// a RecordFormatter, since their output depends on the rest of the record.
+func (w *Writer) applyFormatters(column, val string, record []string) string {
+	cache := w.caches[column]
+	for _, formatter := range w.formatters[column] {
+		if _, ok := formatter.(RecordFormatter); ok {
+			cache = nil
+		}
+	}
+	if cache != nil {
+		if cached, ok := cache.get(val); ok {
+			return cached
+		}
+	}
+	formatted := val
+	for _, formatter := range w.formatters[column] {
+		formatted = w.applyFormatter(formatter, column, formatted, record)
+	}
+	if cache != nil {
+		cache.put(val, formatted)
+	}
+	return formatted
+}
+
+// applyFormatter formats val with formatter, passing the record to it if it is
+// a RecordFormatter or the typed value if it is a TypedFormatter
+func (w *Writer) applyFormatter(formatter Formatter, column, val string, record []string) string {
+	switch f := formatter.(type) {
+	case RecordFormatter:
+		return f.FormatRecord(column, val, record)
+	case TypedFormatter:
+		if typed, ok := parseTyped(w.types[column], val); ok {
+			return f.FormatValue(typed)
+		}
+	}
+	return formatter.Format(val)
+}
+
+// groupDatasets stably sorts rows by the order in which their dataset label,
+// the raw value of the first column, first appears
+func groupDatasets(rows []row) {
+	label := func(r row) string {
+		if len(r.raw) == 0 {
+			return ""
+		}
+		return r.raw[0]
+	}
+	order := map[string]int{}
+	for _, r := range rows {
+		if _, ok := order[label(r)]; !ok {
+			order[label(r)] = len(order)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return order[label(rows[i])] < order[label(rows[j])]
+	})
+}
+
+// lessValues compares two records column by column
+func lessValues(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// normalizeNewlines rewrites all \r\n, \r and \n line endings in value to style
+func normalizeNewlines(value string, style NewlineStyle) string {
+	if !strings.ContainsAny(value, "\r\n") {
+		return value
+	}
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	value = strings.ReplaceAll(value, "\r", "\n")
+	if style != NewlineLF {
+		value = strings.ReplaceAll(value, "\n", string(style))
 	}
-	return final
+	return value
 }
@@ -0,0 +1,1641 @@
+package multiwriter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteTrailerBeforeClose(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		want   string
+	}{
+		{
+			format: JSONFormat,
+			want:   "[\n{\"a\":\"1\",\"b\":\"2\"}\n]\n<!-- end -->",
+		},
+		{
+			format: CSVFormat,
+			opts:   []Option{WithAggregate("b", Sum)},
+			want:   "a,b\n1,2\n,2\n<!-- end -->",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"a", "b"}, tt.format, tt.opts...)
+			if err := w.Write([]string{"1", "2"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteTrailer([]byte("<!-- end -->")); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTrailerBeforeCloseTable(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"a", "b"}, TableFormat, WithAggregate("b", Sum))
+	if err := w.Write([]string{"1", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteTrailer([]byte("end\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"3", "4"}); err != ErrClosed {
+		t.Errorf("got %v writing after the trailer, want ErrClosed", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if n := strings.Count(got, "| A | B |"); n != 1 {
+		t.Errorf("got %d headers in %q, want 1", n, got)
+	}
+	if !strings.HasSuffix(got, "+\nend\n") {
+		t.Errorf("got %q, want the trailer after the table", got)
+	}
+}
+
+func TestWriteTrailerAfterClose(t *testing.T) {
+	for _, format := range []string{JSONFormat, TableFormat, CSVFormat} {
+		t.Run(format, func(t *testing.T) {
+			var want bytes.Buffer
+			w := New(&want, []string{"a", "b"}, format, WithAggregate("b", Sum))
+			w.Write([]string{"1", "2"})
+			w.Close()
+
+			var buf bytes.Buffer
+			w = New(&buf, []string{"a", "b"}, format, WithAggregate("b", Sum))
+			w.Write([]string{"1", "2"})
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteTrailer([]byte("end")); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != want.String()+"end" {
+				t.Errorf("got %q, want %q", got, want.String()+"end")
+			}
+		})
+	}
+}
+
+func TestAppendedColumnOrder(t *testing.T) {
+	prior := map[string]string{}
+	hash := WithRowHashColumn("hash", sha256.New)
+	base := WithBaseline(prior, "a")
+	run := WithRunLengthCount("n")
+	sum := (&rowHash{algo: sha256.New}).sum([]string{"1", "2"})
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"hash,baseline,count", []Option{hash, base, run}, "a,b,hash,change,n\n1,2," + sum + ",added,2\n"},
+		{"baseline,hash,count", []Option{base, hash, run}, "a,b,change,hash,n\n1,2,added," + sum + ",2\n"},
+		{"count,hash,baseline", []Option{run, hash, base}, "a,b,n,hash,change\n1,2,2," + sum + ",added\n"},
+		{"count,baseline,hash", []Option{run, base, hash}, "a,b,n,change,hash\n1,2,2,added," + sum + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"a", "b"}, CSVFormat, tt.opts...)
+			for i := 0; i < 2; i++ {
+				if err := w.Write([]string{"1", "2"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkWriteText(b *testing.B) {
+	w := New(ioutil.Discard, []string{"id", "name", "size"}, TextFormat)
+	record := []string{"42", "report.csv", "1024"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.Write(record); err != nil {
+			b.Fatal(err)
+		}
+	}
+	w.Close()
+}
+
+func TestConcurrentWrite(t *testing.T) {
+	const writers, records = 16, 200
+	var buf bytes.Buffer
+	w := New(&buf, []string{"writer", "record", "payload"}, CSVFormat)
+	payload := strings.Repeat("x", 64)
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				if err := w.Write([]string{strconv.Itoa(g), strconv.Itoa(i), payload}); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%10 == 0 {
+					w.Flush()
+				}
+				if err := w.Error(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != writers*records+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), writers*records+1)
+	}
+	seen := map[string]bool{}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 || fields[2] != payload {
+			t.Fatalf("got interleaved line %q", line)
+		}
+		key := fields[0] + "," + fields[1]
+		if seen[key] {
+			t.Fatalf("got record %s twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+// failingWriter accepts n bytes and then fails every write
+type failingWriter struct {
+	n int
+}
+
+var errFailingWriter = errors.New("write failed")
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > fw.n {
+		n := fw.n
+		fw.n = 0
+		return n, errFailingWriter
+	}
+	fw.n -= len(p)
+	return len(p), nil
+}
+
+func TestTextWriteError(t *testing.T) {
+	w := New(&failingWriter{n: 32}, []string{"id", "name"}, TextFormat, WithSize(16))
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = w.Write([]string{strconv.Itoa(i), "report.csv"})
+	}
+	if err == nil || !strings.Contains(err.Error(), errFailingWriter.Error()) {
+		t.Fatalf("got %v from Write, want %v", err, errFailingWriter)
+	}
+	if w.Error() == nil {
+		t.Error("got no error from Error after a failed Write")
+	}
+}
+
+func TestTextFlushError(t *testing.T) {
+	r, pw := io.Pipe()
+	r.Close()
+	w := New(pw, []string{"id", "name"}, TextFormat)
+	if err := w.Write([]string{"1", "report.csv"}); err != nil {
+		t.Fatalf("got %v from a buffered Write, want nil", err)
+	}
+	w.Flush()
+	if err := w.Error(); err == nil || !strings.Contains(err.Error(), io.ErrClosedPipe.Error()) {
+		t.Errorf("got %v from Error after Flush, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestRawDelimiterHeaderOnlyForCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, TextFormat, WithRawDelimiter("|", "\n"))
+	if err := w.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "---\nid: 1\nname: alice\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOptionsExcludeFileOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := []Option{WithNoHeader()}
+	w, closeFn, err := NewFile(filepath.Join(dir, "out.csv"), []string{"a"}, CSVFormat, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+	if got := len(w.Options()); got != len(opts) {
+		t.Errorf("got %d options from NewFile, want %d", got, len(opts))
+	}
+	w, closeFn, err = NewAppendFile(filepath.Join(dir, "out.csv"), []string{"a"}, CSVFormat, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+	if got := len(w.Options()); got != len(opts) {
+		t.Errorf("got %d options from NewAppendFile, want %d", got, len(opts))
+	}
+	w, closeFn, err = NewPagedFile(filepath.Join(dir, "page-%d.csv"), 10, []string{"a"}, CSVFormat, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+	if got := len(w.Options()); got != len(opts) {
+		t.Errorf("got %d options from NewPagedFile, want %d", got, len(opts))
+	}
+}
+
+func TestAlignedCSVFooter(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name", "size"}, CSVFormat, WithAlignedCSV(true), WithAggregate("size", Sum))
+	w.Write([]string{"alice", "10"})
+	w.Write([]string{"bob", "5"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "name, size\nalice,10\nbob,  5\n,     15\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFlushRateLimit(t *testing.T) {
+	flushes := func(opts ...Option) (int, error) {
+		// counts the flushes before Close, which always flushes
+		n := 0
+		w := NewFunc(func([]byte) error {
+			n++
+			return nil
+		}, []string{"id"}, CSVFormat, append(opts, WithMaxBufferedRows(1))...)
+		for i := 0; i < 5; i++ {
+			if err := w.Write([]string{strconv.Itoa(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		written := n
+		return written, w.Close()
+	}
+	if n, _ := flushes(); n != 5 {
+		t.Errorf("got %d flushes without a rate limit, want 5", n)
+	}
+	if n, _ := flushes(WithFlushRateLimit(time.Hour)); n != 1 {
+		t.Errorf("got %d flushes within the rate limit, want 1", n)
+	}
+}
+
+func TestFlushRateLimitManualFlush(t *testing.T) {
+	var chunks []string
+	w := NewFunc(func(rendered []byte) error {
+		chunks = append(chunks, string(rendered))
+		return nil
+	}, []string{"id"}, CSVFormat, WithMaxBufferedRows(1), WithFlushRateLimit(time.Hour))
+	w.Write([]string{"1"})
+	w.Write([]string{"2"})
+	w.Write([]string{"3"})
+	w.Flush()
+	want := []string{"id\n1\n", "2\n3\n"}
+	if len(chunks) != len(want) || chunks[0] != want[0] || chunks[1] != want[1] {
+		t.Errorf("got chunks %q, want %q", chunks, want)
+	}
+}
+
+func TestSummaryCountsWrittenRows(t *testing.T) {
+	var out, summary bytes.Buffer
+	w := New(&out, []string{"a", "b"}, CSVFormat, WithStderrSummary(&summary))
+	w.Write([]string{"1", "2"})
+	w.Write([]string{"1"})
+	w.Write([]string{"3", "4"})
+	w.Close()
+	if got := summary.String(); !strings.HasPrefix(got, "wrote 2 rows") {
+		t.Errorf("got summary %q, want 2 rows written", got)
+	}
+}
+
+func TestNoHeader(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		want   string
+	}{
+		{CSVFormat, nil, "1,alice\n"},
+		{TSVFormat, nil, "1\talice\n"},
+		{TableFormat, nil, "+---+-------+\n| 1 | alice |\n+---+-------+\n"},
+		{TextFormat, []Option{WithTextHeader(true)}, "---\nid: 1\nname: alice\n"},
+		{MarkdownFormat, nil, "|  |  |\n| --- | --- |\n| 1 | alice |\n"},
+		{HTMLFormat, nil, "<table>\n<tbody>\n<tr><td>1</td><td>alice</td></tr>\n</tbody>\n</table>\n"},
+		{MermaidFormat, nil, "```mermaid\nblock-beta\n  columns 2\n  r0c0[\"1\"] r0c1[\"alice\"]\n```\n"},
+		{JSONFormat, nil, "[\n{\"id\":\"1\",\"name\":\"alice\"}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id", "name"}, tt.format, append(tt.opts, WithNoHeader())...)
+			if err := w.Write([]string{"1", "alice"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatForExtension(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"csv", CSVFormat, ""},
+		{".csv", CSVFormat, ""},
+		{"x.csv", CSVFormat, ""},
+		{"data.2024.MD", MarkdownFormat, ""},
+		{"JSONL", NDJSONFormat, ""},
+		{"README", "", `unknown format for "README"`},
+		{"notes.txt.bak", "", `unknown format for "notes.txt.bak"`},
+		{"", "", `unknown format for ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatForExtension(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeRecords writes records with a new Writer and returns its output
+func writeRecords(t *testing.T, columns []string, format string, records [][]string, opts ...Option) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := New(&buf, columns, format, opts...)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNewline(t *testing.T) {
+	tests := []struct {
+		name  string
+		style NewlineStyle
+		want  string
+	}{
+		{"none", "", "note\n\"a\r\nb\rc\nd\"\n"},
+		{"lf", NewlineLF, "note\n\"a\nb\nc\nd\"\n"},
+		{"crlf", NewlineCRLF, "note\n\"a\r\nb\r\nc\r\nd\"\n"},
+		{"strip", " ", "note\na b c d\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, []string{"note"}, CSVFormat, [][]string{{"a\r\nb\rc\nd"}}, WithNewline(tt.style))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypeHeader(t *testing.T) {
+	got := writeRecords(t, []string{"name", "age", "score"}, CSVFormat, [][]string{{"alice", "30", "9.5"}},
+		WithTypeHeader(true), WithColumnType("age", Int), WithColumnType("score", Float))
+	if want := "name,age,score\nstring,int,float\nalice,30,9.5\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputPipe(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, CSVFormat, WithOutputPipe(func(out io.Writer) io.Writer {
+		return gzip.NewWriter(out)
+	}))
+	if err := w.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if buf.Len() == 0 {
+		t.Error("got no output after Flush, want the pipe flushed")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reading to the end fails unless Close closed the gzip stream
+	got, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name\n1,alice\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	t.Run("skip", func(t *testing.T) {
+		var indexes []int
+		var buf bytes.Buffer
+		w := New(&buf, []string{"id", "name"}, CSVFormat, WithOnError(func(i int, record []string, err error) bool {
+			if !errors.Is(err, ErrRecordLengthMismatch) {
+				t.Errorf("got error %v, want ErrRecordLengthMismatch", err)
+			}
+			indexes = append(indexes, i)
+			return true
+		}))
+		for _, r := range [][]string{{"1", "alice"}, {"2"}, {"3", "carol"}} {
+			if err := w.Write(r); err != nil {
+				t.Fatalf("got %v for a skipped record, want nil", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("got %v, want skipped errors left out of Error", err)
+		}
+		if len(indexes) != 1 || indexes[0] != 1 {
+			t.Errorf("got callback indexes %v, want [1]", indexes)
+		}
+		if got, want := buf.String(), "id,name\n1,alice\n3,carol\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+	t.Run("abort", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := New(&buf, []string{"id", "name"}, CSVFormat, WithOnError(func(int, []string, error) bool {
+			return false
+		}))
+		if err := w.Write([]string{"1", "alice"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write([]string{"2"}); err == nil {
+			t.Fatal("got nil error for an aborting record")
+		}
+		err := w.Write([]string{"3", "carol"})
+		if err == nil || !strings.Contains(err.Error(), "writing aborted at record 1") {
+			t.Errorf("got %v after aborting, want the abort error", err)
+		}
+		if err := w.Close(); err == nil {
+			t.Error("got nil error from Close after aborting")
+		}
+		if got, want := buf.String(), "id,name\n1,alice\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRecordErrors(t *testing.T) {
+	w := New(ioutil.Discard, []string{"id", "name"}, CSVFormat)
+	bad := []string{"2"}
+	for _, r := range [][]string{{"1", "alice"}, bad, {"3", "carol"}, {"4", "dave", "extra"}} {
+		err := w.Write(r)
+		if len(r) == 2 {
+			continue
+		}
+		var re RecordError
+		if !errors.As(err, &re) || !errors.Is(err, ErrRecordLengthMismatch) {
+			t.Errorf("got %v, want a RecordError wrapping ErrRecordLengthMismatch", err)
+		}
+	}
+	bad[0] = "changed"
+	// failed records keep their index among all the records written and a
+	// copy of the record
+	errs := w.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("got %d record errors, want 2", len(errs))
+	}
+	for i, want := range []RecordError{{Index: 1, Record: []string{"2"}}, {Index: 3, Record: []string{"4", "dave", "extra"}}} {
+		if errs[i].Index != want.Index || strings.Join(errs[i].Record, ",") != strings.Join(want.Record, ",") {
+			t.Errorf("got record error %d at %d for %q, want %d for %q", i, errs[i].Index, errs[i].Record, want.Index, want.Record)
+		}
+		if want := fmt.Sprintf("record %d: %s", want.Index, errs[i].Err); errs[i].Error() != want {
+			t.Errorf("got %q, want %q", errs[i].Error(), want)
+		}
+	}
+	if err := w.Error(); err == nil || !strings.Contains(err.Error(), "record 1: ") || !strings.Contains(err.Error(), "record 3: ") {
+		t.Errorf("got %v, want both record errors aggregated", err)
+	}
+
+	w.ResetBuffers()
+	if errs := w.Errors(); len(errs) != 0 {
+		t.Errorf("got %d record errors after resetting, want none", len(errs))
+	}
+}
+
+func TestStableOutput(t *testing.T) {
+	records := [][]string{{"2", "bob"}, {"1", "alice, jr"}, {"3", "carol"}, {"1", "al"}}
+	reversed := make([][]string, len(records))
+	for i, r := range records {
+		reversed[len(records)-1-i] = r
+	}
+	for _, format := range []string{CSVFormat, JSONFormat, TableFormat} {
+		t.Run(format, func(t *testing.T) {
+			a := writeRecords(t, []string{"id", "name"}, format, records, WithStableOutput())
+			b := writeRecords(t, []string{"id", "name"}, format, reversed, WithStableOutput())
+			if a != b {
+				t.Errorf("got %q and %q, want byte-identical output", a, b)
+			}
+		})
+	}
+	got := writeRecords(t, []string{"id", "name"}, CSVFormat, records, WithStableOutput())
+	if want := "id,name\n1,al\n1,\"alice, jr\"\n2,bob\n3,carol\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRepeatHeaderEvery(t *testing.T) {
+	records := [][]string{{"1"}, {"2"}, {"3"}, {"4"}, {"5"}}
+	tests := []struct {
+		format string
+		opts   []Option
+		want   string
+	}{
+		{TextFormat, []Option{WithTextHeader(true)}, "columns: id\n---\nid: 1\n---\nid: 2\ncolumns: id\n---\nid: 3\n---\nid: 4\ncolumns: id\n---\nid: 5\n"},
+		{TableFormat, nil, "+----+\n| ID |\n+----+\n|  1 |\n|  2 |\n| ID |\n|  3 |\n|  4 |\n| ID |\n|  5 |\n+----+\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got := writeRecords(t, []string{"id"}, tt.format, records, append(tt.opts, WithRepeatHeaderEvery(2))...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.csv")
+	if err := ioutil.WriteFile(path, []byte("old content that is longer\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	w, closeFn, err := NewFile(path, []string{"id", "name"}, CSVFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := closeFn(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name\n1,alice\n"; string(got) != want {
+		t.Errorf("got %q, want the truncated file to hold %q", got, want)
+	}
+	if err := w.Write([]string{"2", "bob"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v writing after the close function, want ErrClosed", err)
+	}
+
+	_, _, err = NewFile(filepath.Join(dir, "missing", "out.csv"), []string{"id"}, CSVFormat)
+	if err == nil || !strings.Contains(err.Error(), "error creating file") {
+		t.Errorf("got %v, want an error creating the file", err)
+	}
+}
+
+func TestNewAppendFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "id,name\n1,alice\n2,bob\n"},
+		// the appended rows continue the existing table
+		{MarkdownFormat, "| id | name |\n| --- | --- |\n| 1 | alice |\n| 2 | bob |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(dir, "out."+tt.format)
+			// the header is only written to the new file
+			for _, r := range [][]string{{"1", "alice"}, {"2", "bob"}} {
+				w, closeFn, err := NewAppendFile(path, []string{"id", "name"}, tt.format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Write(r); err != nil {
+					t.Fatal(err)
+				}
+				if err := closeFn(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// outputs without a Stat method are treated as empty
+	var buf bytes.Buffer
+	buf.WriteString("id\n1\n")
+	w := New(&buf, []string{"id"}, CSVFormat, WithAppend())
+	w.Write([]string{"2"})
+	w.Close()
+	if got, want := buf.String(), "id\n1\nid\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatFromExtension(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{"report.csv", CSVFormat, ""},
+		{"/tmp/out.v2/REPORT.JSON", JSONFormat, ""},
+		{"events.jsonl", NDJSONFormat, ""},
+		{"data.tab", TSVFormat, ""},
+		{"page.htm", HTMLFormat, ""},
+		{"book.xlsx", XLSXFormat, ""},
+		{"notes.yml", YAMLFormat, ""},
+		{"archive.tar.gz", "", `unknown format for extension ".gz"`},
+		{"/tmp/out.v2/README", "", `unknown format for extension ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := FormatFromExtension(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotedTextValues(t *testing.T) {
+	columns := []string{"a", "b", "c", "d"}
+	records := [][]string{{"plain", "a: b", `say "hi"`, "x\ny"}}
+	tests := []struct {
+		quote bool
+		want  string
+	}{
+		{true, "---\na: plain\nb: \"a: b\"\nc: \"say \\\"hi\\\"\"\nd: \"x\\ny\"\n"},
+		{false, "---\na: plain\nb: a: b\nc: say \"hi\"\nd: \"x\\ny\"\n"},
+	}
+	for _, tt := range tests {
+		got := writeRecords(t, columns, TextFormat, records, WithQuotedTextValues(tt.quote))
+		if got != tt.want {
+			t.Errorf("quote %v: got %q, want %q", tt.quote, got, tt.want)
+		}
+	}
+	// quoted values can be parsed back as Go string literals
+	got := writeRecords(t, columns, TextFormat, records, WithQuotedTextValues(true))
+	for i, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n")[1:] {
+		v := strings.TrimPrefix(line, columns[i]+": ")
+		if strings.HasPrefix(v, `"`) {
+			var err error
+			if v, err = strconv.Unquote(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if v != records[0][i] {
+			t.Errorf("got %q parsing column %s, want %q", v, columns[i], records[0][i])
+		}
+	}
+}
+
+func TestNewFunc(t *testing.T) {
+	var batches []string
+	w := NewFunc(func(rendered []byte) error {
+		batches = append(batches, string(rendered))
+		return nil
+	}, []string{"id"}, CSVFormat)
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	// a flush with nothing rendered doesn't invoke fn
+	w.Flush()
+	if err := w.Write([]string{"2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(batches, "|"), "id\n1\n|2\n"; got != want {
+		t.Errorf("got batches %q, want %q", got, want)
+	}
+
+	w = NewFunc(func([]byte) error {
+		return errors.New("queue unavailable")
+	}, []string{"id"}, CSVFormat)
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "queue unavailable") {
+		t.Errorf("got %v, want the error returned by fn", err)
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	var seen []string
+	check := FuncFormatter(func(v string) string {
+		seen = append(seen, v)
+		return v
+	})
+	got := writeRecords(t, []string{"v"}, CSVFormat,
+		[][]string{{"a\xffb"}, {"\xff\xfe"}, {"héllo"}, {"\xe2\x82"}},
+		WithValidateUTF8('?'), WithFormatter("v", check))
+	if want := "v\na?b\n?\nhéllo\n?\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, v := range seen {
+		if !utf8.ValidString(v) {
+			t.Errorf("got invalid UTF-8 %q passed to a formatter", v)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	var records [][]string
+	for i := 1; i <= 5; i++ {
+		records = append(records, []string{strconv.Itoa(i)})
+	}
+	for _, tt := range []struct {
+		n    int
+		want string
+	}{
+		{2, "id\n4\n5\n"},
+		{10, "id\n1\n2\n3\n4\n5\n"},
+	} {
+		if got := writeRecords(t, []string{"id"}, CSVFormat, records, WithTail(tt.n)); got != tt.want {
+			t.Errorf("tail %d: got %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSeparator(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "id\n1\n\n2\n"},
+		{TableFormat, "+----+\n| ID |\n+----+\n|  1 |\n|    |\n|  2 |\n+----+\n"},
+		{TextFormat, "---\nid: 1\n---\n---\nid: 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id"}, tt.format)
+			if err := w.Write([]string{"1"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteSeparator(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write([]string{"2"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if err := w.WriteSeparator(); !errors.Is(err, ErrClosed) {
+				t.Errorf("got %v after Close, want ErrClosed", err)
+			}
+		})
+	}
+}
+
+func TestMaxBytesFormatter(t *testing.T) {
+	tests := []struct {
+		max   int
+		value string
+		want  string
+	}{
+		{5, "hello", "hello"},
+		{3, "hello", "hel"},
+		{0, "hello", ""},
+		{-1, "hello", ""},
+		{2, "héllo", "h"},  // é is 2 bytes, cut before it
+		{3, "héllo", "hé"}, // cut right after é
+		{5, "日本語", "日"},    // each rune is 3 bytes
+		{6, "日本語", "日本"},   // exactly on a boundary
+		{4, "a😀b", "a"},    // 😀 is 4 bytes
+		{5, "a😀b", "a😀"},   // fits exactly
+		{10, "", ""},
+	}
+	for _, tt := range tests {
+		got := MaxBytesFormatter{Max: tt.max}.Format(tt.value)
+		if got != tt.want {
+			t.Errorf("Max %d of %q: got %q, want %q", tt.max, tt.value, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Max %d of %q: got invalid UTF-8 %q", tt.max, tt.value, got)
+		}
+	}
+}
+
+func TestStderrSummary(t *testing.T) {
+	var out, summary bytes.Buffer
+	w := New(&out, []string{"a", "b"}, CSVFormat, WithStderrSummary(&summary))
+	w.Write([]string{"1", "2"})
+	w.Write([]string{"1"})
+	w.Write([]string{"3", "4"})
+	if summary.Len() != 0 {
+		t.Errorf("got summary %q before Close, want none", summary.String())
+	}
+	w.Close()
+	w.Close()
+	re := regexp.MustCompile(`^wrote 2 rows \((\d+) bytes\) in \S+ with 1 errors\n$`)
+	m := re.FindStringSubmatch(summary.String())
+	if m == nil {
+		t.Fatalf("got summary %q, want it to match %s once", summary.String(), re)
+	}
+	if m[1] != strconv.Itoa(out.Len()) {
+		t.Errorf("got %s bytes in the summary, want %d", m[1], out.Len())
+	}
+	if got, want := out.String(), "a,b\n1,2\n3,4\n"; got != want {
+		t.Errorf("got output %q, want %q without the summary", got, want)
+	}
+}
+
+func TestSync(t *testing.T) {
+	f, err := ioutil.TempFile("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	w := New(f, []string{"id"}, CSVFormat, WithCloseUnderlying())
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id\n1\n"; string(got) != want {
+		t.Errorf("got %q on disk after Sync, want %q", got, want)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// outputs other than files are only flushed
+	var buf bytes.Buffer
+	w = New(&buf, []string{"id"}, CSVFormat)
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id\n1\n"; got != want {
+		t.Errorf("got %q after Sync, want %q", got, want)
+	}
+}
+
+func TestHeaderSidecar(t *testing.T) {
+	var sidecar bytes.Buffer
+	got := writeRecords(t, []string{"id", "full name"}, CSVFormat, [][]string{{"1", "alice"}},
+		WithHeaderSidecar(&sidecar), WithTypeHeader(true), WithColumnType("id", Int),
+		WithHeaderLabels(map[string]string{"full name": "name, full"}))
+	if want := "1,alice\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	if got, want := sidecar.String(), "id,\"name, full\"\nint,string\n"; got != want {
+		t.Errorf("got sidecar %q, want %q", got, want)
+	}
+}
+
+func TestBaseFormatter(t *testing.T) {
+	tests := []struct {
+		f     BaseFormatter
+		value string
+		want  string
+	}{
+		{BaseFormatter{From: 10, To: 16, Prefix: "0x"}, "255", "0xff"},
+		{BaseFormatter{From: 10, To: 16, Prefix: "0x"}, "-255", "-0xff"},
+		{BaseFormatter{From: 16, To: 10}, "FF", "255"},
+		{BaseFormatter{From: 10, To: 2}, "5", "101"},
+		{BaseFormatter{From: 0, To: 10}, "0x1f", "31"},
+		{BaseFormatter{From: 10, To: 16}, "-9223372036854775808", "-8000000000000000"},
+		{BaseFormatter{From: 10, To: 16}, "12.5", "12.5"},
+		{BaseFormatter{From: 10, To: 16}, "n/a", "n/a"},
+		{BaseFormatter{From: 10, To: 16}, "99999999999999999999", "99999999999999999999"},
+		{BaseFormatter{From: 10, To: 1}, "5", "5"},
+		{BaseFormatter{From: 10, To: 37}, "5", "5"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Format(tt.value); got != tt.want {
+			t.Errorf("%+v of %q: got %q, want %q", tt.f, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	prefix, suffix := []byte("<pre>\n"), []byte("</pre>\n")
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, CSVFormat, WithEnvelope(prefix, suffix))
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if err := w.Write([]string{"2"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, want := buf.String(), "<pre>\nid\n1\n2\n</pre>\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// the envelope is written even without any output
+	got := writeRecords(t, []string{"id"}, CSVFormat, nil, WithNoHeader(), WithEnvelope(prefix, suffix))
+	if want := "<pre>\n</pre>\n"; got != want {
+		t.Errorf("got %q for empty output, want %q", got, want)
+	}
+}
+
+func TestFormatterCache(t *testing.T) {
+	var calls []string
+	upper := FuncFormatter(func(v string) string {
+		calls = append(calls, v)
+		return strings.ToUpper(v)
+	})
+	// with room for two entries: a and b miss, a hits, c evicts b, b misses
+	// and evicts a, a misses and evicts c, b hits
+	inputs := []string{"a", "b", "a", "c", "b", "a", "b"}
+	records := make([][]string, len(inputs))
+	for i, v := range inputs {
+		records[i] = []string{v}
+	}
+	got := writeRecords(t, []string{"v"}, CSVFormat, records, WithFormatter("v", upper), WithFormatterCache("v", 2))
+	if want := "v\nA\nB\nA\nC\nB\nA\nB\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if hits := len(inputs) - len(calls); hits != 2 {
+		t.Errorf("got %d cache hits, want 2", hits)
+	}
+	if got, want := strings.Join(calls, ","), "a,b,c,b,a"; got != want {
+		t.Errorf("got formatter calls %s, want %s after evictions", got, want)
+	}
+	w := New(ioutil.Discard, []string{"v"}, CSVFormat, WithFormatterCache("v", 2))
+	for _, v := range inputs {
+		w.Write([]string{v})
+	}
+	if got := w.caches["v"].order.Len(); got != 2 {
+		t.Errorf("got %d cached entries, want the size of 2", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "1,\"a, b\"\n"},
+		{TSVFormat, "1\ta, b\n"},
+		{TableFormat, "+---+------+\n| 1 | a, b |\n+---+------+\n"},
+		{TextFormat, "---\nid: 1\nname: a, b\n"},
+		{LogfmtFormat, "id=1 name=\"a, b\"\n"},
+		{JSONFormat, "{\"id\":\"1\",\"name\":\"a, b\"}\n"},
+		{NDJSONFormat, "{\"id\":\"1\",\"name\":\"a, b\"}\n"},
+		{PGCopyFormat, "1\ta, b\n"},
+		{MarkdownFormat, "| 1 | a, b |\n"},
+		{YAMLFormat, "- id: \"1\"\n  name: \"a, b\"\n"},
+		{HTMLFormat, "<tr><td>1</td><td>a, b</td></tr>\n"},
+		{MermaidFormat, "```mermaid\nblock-beta\n  columns 2\n  r0c0[\"1\"] r0c1[\"a, b\"]\n```\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			w := New(ioutil.Discard, []string{"id", "name"}, tt.format, WithNoHeader())
+			got, err := w.Render([]string{"1", "a, b"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if w.rowCount != 0 || len(w.rows) != 0 || w.table.NumLines() != 0 {
+				t.Error("got records buffered, want Render to leave the buffers untouched")
+			}
+		})
+	}
+
+	w := New(ioutil.Discard, []string{"id", "name"}, CSVFormat)
+	for _, record := range [][]string{{"1"}, {"1", "a", "extra"}} {
+		if _, err := w.Render(record); !errors.Is(err, ErrRecordLengthMismatch) {
+			t.Errorf("got %v rendering %q, want ErrRecordLengthMismatch", err, record)
+		}
+	}
+	// records are checked against the input columns when projecting
+	w = New(ioutil.Discard, []string{"id", "name"}, CSVFormat, WithColumns([]string{"name"}))
+	if _, err := w.Render([]string{"alice"}); !errors.Is(err, ErrRecordLengthMismatch) {
+		t.Errorf("got %v, want ErrRecordLengthMismatch", err)
+	}
+}
+
+func TestLockColumns(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, CSVFormat, WithLockColumns(), WithDynamicColumns())
+	if err := w.Write([]string{"1", "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Reset([]string{"id", "email"})
+	if err := w.Error(); !errors.Is(err, ErrColumnsLocked) {
+		t.Errorf("got %v from Reset, want ErrColumnsLocked", err)
+	}
+	if err := w.ResetOutput(&buf, []string{"id"}); !errors.Is(err, ErrColumnsLocked) {
+		t.Errorf("got %v from ResetOutput, want ErrColumnsLocked", err)
+	}
+	if err := w.AddColumn("email"); !errors.Is(err, ErrColumnsLocked) {
+		t.Errorf("got %v from AddColumn, want ErrColumnsLocked", err)
+	}
+	if got := strings.Join(w.columns, ","); got != "id,name" {
+		t.Errorf("got columns %s, want them unchanged", got)
+	}
+	// the writer keeps working with its columns
+	if err := w.Write([]string{"2", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, want := buf.String(), "id,name\n1,alice\n2,bob\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// resetting to the same columns is allowed
+	w = New(ioutil.Discard, []string{"id"}, CSVFormat, WithLockColumns())
+	w.Reset([]string{"id"})
+	if err := w.Error(); err != nil {
+		t.Errorf("got %v resetting to the same columns, want nil", err)
+	}
+}
+
+func TestRecordSize(t *testing.T) {
+	record := []string{"1", "héllo, world"}
+	for _, format := range []string{CSVFormat, TextFormat, JSONFormat, NDJSONFormat, LogfmtFormat} {
+		t.Run(format, func(t *testing.T) {
+			w := New(ioutil.Discard, []string{"id", "name"}, format, WithNoHeader())
+			size, err := w.RecordSize(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a lone record streams as it is rendered, apart from the
+			// JSON array's brackets
+			out := writeRecords(t, []string{"id", "name"}, format, [][]string{record}, WithNoHeader())
+			if format == JSONFormat {
+				out = strings.TrimSuffix(strings.TrimPrefix(out, "[\n"), "]\n")
+			}
+			if size != len(out) {
+				t.Errorf("got size %d, want %d bytes of %q", size, len(out), out)
+			}
+		})
+	}
+	w := New(ioutil.Discard, []string{"id", "name"}, CSVFormat)
+	if _, err := w.RecordSize([]string{"1"}); !errors.Is(err, ErrRecordLengthMismatch) {
+		t.Errorf("got %v, want ErrRecordLengthMismatch", err)
+	}
+}
+
+func TestSheetTypes(t *testing.T) {
+	got := writeRecords(t, []string{"name", "amount", "when"}, CSVFormat, [][]string{{"a", "1.5", "2024-01-02"}},
+		WithSheetTypes(map[string]string{"amount": "number", "when": "date"}))
+	if want := "name,amount,when\n,number,date\na,1.5,2024-01-02\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the hints follow the type header
+	got = writeRecords(t, []string{"amount"}, CSVFormat, [][]string{{"1.5"}},
+		WithSheetTypes(map[string]string{"amount": "number"}), WithTypeHeader(true), WithColumnType("amount", Float))
+	if want := "amount\nfloat\nnumber\n1.5\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRecordLengthMismatch(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		record []string
+		opts   []Option
+	}{
+		{"short", []string{"1"}, nil},
+		{"long", []string{"1", "alice", "x"}, nil},
+		// appended columns aren't expected from the caller
+		{"appended", []string{"1", "alice", "hash"}, []Option{WithRowHashColumn("hash", sha256.New)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(ioutil.Discard, []string{"id", "name"}, CSVFormat, tt.opts...)
+			err := w.Write(tt.record)
+			if !errors.Is(err, ErrRecordLengthMismatch) {
+				t.Fatalf("got %v, want ErrRecordLengthMismatch", err)
+			}
+			var re RecordError
+			if !errors.As(err, &re) || re.Index != 0 {
+				t.Errorf("got %v, want a RecordError for record 0", err)
+			}
+			if !errors.Is(w.Close(), ErrRecordLengthMismatch) {
+				t.Errorf("got %v from Close, want ErrRecordLengthMismatch", w.Error())
+			}
+		})
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name"}, CSVFormat)
+	if err := w.WriteAll([][]string{{"1", "alice"}, {"2", "bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := w.WriteAll([][]string{{"3", "carol"}, {"4"}, {"5", "eve"}})
+	if !errors.Is(err, ErrRecordLengthMismatch) {
+		t.Errorf("got %v, want the first error", err)
+	}
+	w.Close()
+	if got, want := buf.String(), "id,name\n1,alice\n2,bob\n3,carol\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := w.WriteAll([][]string{{"6", "frank"}}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"color", "\x1b[31mred\x1b[0m", "red"},
+		{"bold and color", "\x1b[1;32mok\x1b[m done", "ok done"},
+		{"cursor", "\x1b[2Kline\x1b[?25h", "line"},
+		{"osc hyperlink bel", "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link"},
+		{"osc hyperlink st", "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"osc title", "\x1b]0;title\x07text", "text"},
+		{"plain", "no escapes [31m", "no escapes [31m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, []string{"v"}, CSVFormat, [][]string{{tt.value}}, WithStripANSI(true))
+			if want := "v\n" + tt.want + "\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name", "email"}, CSVFormat, WithColumns([]string{"email", "id"}),
+		WithFormatter("email", FuncFormatter(strings.ToUpper)))
+	if err := w.Write([]string{"1", "alice", "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"2", "bob"}); !errors.Is(err, ErrRecordLengthMismatch) {
+		t.Errorf("got %v for a record missing input columns, want ErrRecordLengthMismatch", err)
+	}
+	w.Close()
+	if got, want := buf.String(), "email,id\nA@EXAMPLE.COM,1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	w = New(ioutil.Discard, []string{"id"}, CSVFormat, WithColumns([]string{"id", "missing"}))
+	if err := w.Error(); err == nil || !strings.Contains(err.Error(), `unknown output column "missing"`) {
+		t.Errorf("got %v, want the unknown column reported", err)
+	}
+}
+
+func TestExcludeColumns(t *testing.T) {
+	columns := []string{"id", "name", "email", "token"}
+	record := []string{"1", "alice", "a@example.com", "secret"}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"exclude", []Option{WithExcludeColumns([]string{"token"})}, "id,name,email\n1,alice,a@example.com\n"},
+		{"with columns", []Option{WithColumns([]string{"email", "token", "id"}), WithExcludeColumns([]string{"token"})}, "email,id\na@example.com,1\n"},
+		{"excluded not output", []Option{WithColumns([]string{"id", "name"}), WithExcludeColumns([]string{"token"})}, "id,name\n1,alice\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeRecords(t, columns, CSVFormat, [][]string{record}, tt.opts...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	w := New(ioutil.Discard, columns, CSVFormat, WithExcludeColumns([]string{"missing"}))
+	if err := w.Error(); err == nil || !strings.Contains(err.Error(), `unknown excluded column "missing"`) {
+		t.Errorf("got %v, want the unknown column reported", err)
+	}
+}
+
+func TestWriteFlush(t *testing.T) {
+	tests := []struct {
+		format string
+		after  []string
+		final  string
+	}{
+		{CSVFormat, []string{"id\n1\n", "id\n1\n2\n"}, "id\n1\n2\n"},
+		{TableFormat, []string{"+----+\n| ID |\n+----+\n|  1 |\n", "+----+\n| ID |\n+----+\n|  1 |\n|  2 |\n"}, "+----+\n| ID |\n+----+\n|  1 |\n|  2 |\n+----+\n"},
+		{JSONFormat, []string{"[\n{\"id\":\"1\"}", "[\n{\"id\":\"1\"},\n{\"id\":\"2\"}"}, "[\n{\"id\":\"1\"},\n{\"id\":\"2\"}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id"}, tt.format)
+			for i, want := range tt.after {
+				if err := w.WriteFlush([]string{strconv.Itoa(i + 1)}); err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != want {
+					t.Errorf("got %q after record %d, want %q", got, i+1, want)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.final {
+				t.Errorf("got %q, want %q", got, tt.final)
+			}
+		})
+	}
+}
+
+func TestFormatterAt(t *testing.T) {
+	// both columns share a name, so only an index tells them apart
+	got := writeRecords(t, []string{"v", "v"}, CSVFormat, [][]string{{"a", "b"}},
+		WithFormatterAt(1, FuncFormatter(strings.ToUpper)),
+		WithFormatterAt(1, BasicFormatter{FmtString: "<%s>"}))
+	if want := "v,v\na,<B>\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// indexes refer to the output columns
+	got = writeRecords(t, []string{"id", "name"}, CSVFormat, [][]string{{"1", "alice"}},
+		WithColumns([]string{"name"}), WithFormatterAt(0, FuncFormatter(strings.ToUpper)))
+	if want := "name\nALICE\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, index := range []int{-1, 2} {
+		var buf bytes.Buffer
+		w := New(&buf, []string{"a", "b"}, CSVFormat, WithFormatterAt(index, FuncFormatter(strings.ToUpper)))
+		want := fmt.Sprintf("formatter index %d out of range for 2 columns", index)
+		if err := w.Error(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want %q", err, want)
+		}
+		// the writer still works without the formatter
+		w.Write([]string{"x", "y"})
+		w.Close()
+		if got := buf.String(); got != "a,b\nx,y\n" {
+			t.Errorf("got %q, want the values unformatted", got)
+		}
+	}
+}
+
+func TestTextHeader(t *testing.T) {
+	records := [][]string{{"1", "alice"}}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"off", nil, "---\nid: 1\nname: alice\n"},
+		{"names", []Option{WithTextHeader(true)}, "columns: id, name\n---\nid: 1\nname: alice\n"},
+		{"types", []Option{WithTextHeader(true), WithColumnType("id", Int)}, "columns: id (int), name (string)\n---\nid: 1\nname: alice\n"},
+		{"labels", []Option{WithTextHeader(true), WithHeaderLabels(map[string]string{"name": "Name"})}, "columns: id, Name\n---\nid: 1\nname: alice\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, []string{"id", "name"}, TextFormat, records, tt.opts...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderLabels(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "id,Full name\n1,alice!\n"},
+		{TableFormat, "+----+-----------+\n| ID | FULL NAME |\n+----+-----------+\n|  1 | alice!    |\n+----+-----------+\n"},
+		{MarkdownFormat, "| id | Full name |\n| --- | --- |\n| 1 | alice! |\n"},
+		{HTMLFormat, "<table>\n<thead>\n<tr><th>id</th><th>Full name</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td><td>alice!</td></tr>\n</tbody>\n</table>\n"},
+		{MermaidFormat, "```mermaid\nblock-beta\n  columns 2\n  hc0[\"id\"] hc1[\"Full name\"]\n  r0c0[\"1\"] r0c1[\"alice!\"]\n```\n"},
+		// only the header line is labelled, records keep the column names
+		{TextFormat, "columns: id, Full name\n---\nid: 1\nname: alice!\n"},
+		{JSONFormat, "[\n{\"id\":\"1\",\"name\":\"alice!\"}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// formatters still refer to the column name
+			got := writeRecords(t, []string{"id", "name"}, tt.format, [][]string{{"1", "alice"}},
+				WithHeaderLabels(map[string]string{"name": "Full name"}), WithTextHeader(true),
+				WithFormatter("name", FuncFormatter(func(s string) string { return s + "!" })))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// closeCounter counts the calls to its Close method
+type closeCounter struct {
+	bytes.Buffer
+	closes int
+}
+
+func (cc *closeCounter) Close() error {
+	cc.closes++
+	return nil
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	out := &closeCounter{}
+	w := New(out, []string{"id"}, JSONFormat, WithCloseUnderlying())
+	if err := w.Write([]string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"2"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v from Write, want ErrClosed", err)
+	}
+	if err := w.WriteFlush([]string{"2"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v from WriteFlush, want ErrClosed", err)
+	}
+	// a second Close writes nothing and doesn't close the output again
+	if err := w.Close(); err != nil {
+		t.Errorf("got %v from the second Close, want nil", err)
+	}
+	if got, want := out.String(), "[\n{\"id\":\"1\"}\n]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if out.closes != 1 {
+		t.Errorf("got the output closed %d times, want once", out.closes)
+	}
+
+	// a second Close returns the same error
+	w = New(ioutil.Discard, []string{"id"}, CSVFormat)
+	w.Write([]string{"1", "2"})
+	first, second := w.Close(), w.Close()
+	if first == nil || second == nil || first.Error() != second.Error() {
+		t.Errorf("got %v and %v, want the same error from both calls to Close", first, second)
+	}
+}
+
+// failingCloser fails to close
+type failingCloser struct {
+	bytes.Buffer
+}
+
+func (failingCloser) Close() error {
+	return errors.New("close failed")
+}
+
+func TestCloseUnderlying(t *testing.T) {
+	// the output is only closed with the option
+	out := &closeCounter{}
+	w := New(out, []string{"id"}, CSVFormat)
+	w.Write([]string{"1"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.closes != 0 {
+		t.Errorf("got the output closed %d times without WithCloseUnderlying, want 0", out.closes)
+	}
+
+	// every page output is closed, each once its page is finished
+	var pages []*closeCounter
+	first := &closeCounter{}
+	w = New(first, []string{"id"}, CSVFormat, WithCloseUnderlying(), WithPageSize(1),
+		WithPageOutput(func(int) (io.Writer, error) {
+			pages = append(pages, &closeCounter{})
+			return pages[len(pages)-1], nil
+		}))
+	for _, id := range []string{"1", "2", "3"} {
+		w.Write([]string{id})
+		if id == "2" && (first.closes != 1 || pages[0].closes != 0) {
+			t.Errorf("got %d and %d closes on the second page, want 1 and 0", first.closes, pages[0].closes)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, out := range append([]*closeCounter{first}, pages...) {
+		if out.closes != 1 {
+			t.Errorf("got page %d closed %d times, want once", i+1, out.closes)
+		}
+		if got, want := out.String(), "id\n"+strconv.Itoa(i+1)+"\n"; got != want {
+			t.Errorf("got %q in page %d, want %q", got, i+1, want)
+		}
+	}
+
+	w = New(&failingCloser{}, []string{"id"}, CSVFormat, WithCloseUnderlying())
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "error closing output: close failed") {
+		t.Errorf("got %v, want the close error", err)
+	}
+}
+
+func TestRecordFormatter(t *testing.T) {
+	type call struct {
+		column, value string
+		record        []string
+	}
+	var calls []call
+	// the amount is prefixed with the currency held by another column
+	currency := RecordFuncFormatter(func(column, value string, record []string) string {
+		calls = append(calls, call{column, value, record})
+		if record == nil {
+			return value
+		}
+		return record[0] + " " + value
+	})
+	got := writeRecords(t, []string{"currency", "amount"}, CSVFormat, [][]string{{"EUR", "5"}, {"USD", "7"}},
+		WithFormatter("amount", BasicFormatter{FmtString: "%s.00"}), WithFormatter("amount", currency))
+	if want := "currency,amount\nEUR,EUR 5.00\nUSD,USD 7.00\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// it sees the value formatted so far and the raw record
+	if len(calls) != 2 || calls[0].column != "amount" || calls[0].value != "5.00" || calls[0].record[1] != "5" {
+		t.Errorf("got calls %+v, want the column, formatted value and raw record", calls)
+	}
+	// Format is called without a column or record
+	calls = nil
+	if got := currency.Format("x"); got != "x" || calls[0].column != "" || calls[0].record != nil {
+		t.Errorf("got %q and calls %+v from Format, want no column or record", got, calls)
+	}
+}
+
+func TestConditionalFormatter(t *testing.T) {
+	mark := FuncFormatter(func(s string) string { return "!" + s })
+	// cond is passed the raw status, and f applies after the formatters added
+	// before it
+	failed := func(record []string) bool { return record[1] == "failed" }
+	got := writeRecords(t, []string{"size", "status"}, CSVFormat, [][]string{{"10", "ok"}, {"20", "failed"}},
+		WithFormatter("size", FuncFormatter(func(s string) string { return s + "B" })),
+		WithConditionalFormatter("size", failed, mark),
+		WithFormatter("status", FuncFormatter(strings.ToUpper)))
+	if want := "size,status\n10B,OK\n!20B,FAILED\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// records are in the order of the output columns
+	var seen [][]string
+	got = writeRecords(t, []string{"name", "size", "status"}, CSVFormat, [][]string{{"a", "10", "ok"}, {"b", "20", "failed"}},
+		WithColumns([]string{"status", "size"}),
+		WithConditionalFormatter("size", func(record []string) bool {
+			seen = append(seen, record)
+			return record[0] == "failed"
+		}, mark))
+	if want := "status,size\nok,10\nfailed,!20\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := fmt.Sprint(seen), "[[ok 10] [failed 20]]"; got != want {
+		t.Errorf("got records %s passed to cond, want %s", got, want)
+	}
+}
+
+func TestEmptyValue(t *testing.T) {
+	// a formatter returning an empty value is replaced too
+	drop := FuncFormatter(func(v string) string {
+		if v == "-" {
+			return ""
+		}
+		return v
+	})
+	records := [][]string{{"1", ""}, {"2", "-"}, {"3", " "}}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "id,name\n1,N/A\n2,N/A\n3,\" \"\n"},
+		{JSONFormat, "[\n{\"id\":\"1\",\"name\":\"N/A\"},\n{\"id\":\"2\",\"name\":\"N/A\"},\n{\"id\":\"3\",\"name\":\" \"}\n]\n"},
+		{MarkdownFormat, "| id | name |\n| --- | --- |\n| 1 | N/A |\n| 2 | N/A |\n| 3 |   |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got := writeRecords(t, []string{"id", "name"}, tt.format, records, WithEmptyValue("N/A"), WithFormatter("name", drop))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowTransformer(t *testing.T) {
+	// drop inactive users, then derive the display name
+	active := func(record []string) ([]string, bool) {
+		return record, record[2] == "yes"
+	}
+	var seen []string
+	display := func(record []string) ([]string, bool) {
+		seen = append(seen, record[0])
+		return []string{record[0], strings.Title(record[1]), record[2]}, true
+	}
+	records := [][]string{{"1", "alice", "yes"}, {"2", "bob", "no"}, {"3", "carol", "yes"}}
+	got := writeRecords(t, []string{"id", "name", "active"}, CSVFormat, records,
+		WithRowTransformer(active), WithRowTransformer(display))
+	if want := "id,name,active\n1,Alice,yes\n3,Carol,yes\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := strings.Join(seen, ","); got != "1,3" {
+		t.Errorf("got %s passed to the second transformer, want only the kept records 1,3", got)
+	}
+
+	// a rewritten record must still match the columns
+	w := New(ioutil.Discard, []string{"id", "name"}, CSVFormat, WithRowTransformer(func(record []string) ([]string, bool) {
+		return record[:1], true
+	}))
+	if err := w.Write([]string{"1", "alice"}); !errors.Is(err, ErrRecordLengthMismatch) {
+		t.Errorf("got %v, want ErrRecordLengthMismatch", err)
+	}
+}
@@ -0,0 +1,161 @@
+package multiwriter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// paging describes how records are split into pages
+type paging struct {
+	size   int
+	rows   int
+	page   int
+	onPage func(page int, out io.Writer) error
+	output func(page int) (io.Writer, error)
+}
+
+// WithPageSize splits the output into pages of n records. Each page is
+// finished as Close would finish the output, e.g. with the footer and the
+// closing of a JSON array, and the next page starts with the header again,
+// on the same output or on the one returned by the func set with
+// WithPageOutput. Records held in memory by options such as WithSort are
+// sorted within each page.
+func WithPageSize(n int) Option {
+	return func(w *Writer) {
+		w.page().size = n
+	}
+}
+
+// WithOnPage calls fn with the number of each page, starting at 1, and its
+// output once the page is finished, e.g. to wait for the user before showing
+// the next page. An error stops the writer and is returned by Close.
+func WithOnPage(fn func(page int, out io.Writer) error) Option {
+	return func(w *Writer) {
+		w.page().onPage = fn
+	}
+}
+
+// WithPageOutput calls fn for the output of each page after the first, which
+// is written to the writer's output, e.g. to rotate to a new file per page.
+// An error stops the writer and is returned by Close.
+func WithPageOutput(fn func(page int) (io.Writer, error)) Option {
+	return func(w *Writer) {
+		w.page().output = fn
+	}
+}
+
+// NewPagedFile creates a file for each page of n records, named by formatting
+// pattern with the page number, e.g. "export-%03d.csv", and returns a new
+// Writer for them along with a func that closes the writer and the last
+// file. Each file holds a complete output with its own header and is closed
+// once its page is finished.
+func NewPagedFile(pattern string, n int, columns []string, format string, opts ...Option) (*Writer, func() error, error) {
+	f, err := os.Create(fmt.Sprintf(pattern, 1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating file: %s", err)
+	}
+	output := func(page int) (io.Writer, error) {
+		f, err := os.Create(fmt.Sprintf(pattern, page))
+		if err != nil {
+			return nil, fmt.Errorf("error creating file: %s", err)
+		}
+		return f, nil
+	}
+	w := New(f, columns, format, append(opts[:len(opts):len(opts)], WithPageSize(n), WithPageOutput(output), WithCloseUnderlying())...)
+	w.opts = opts
+	return w, w.Close, nil
+}
+
+// page returns the writer's paging, creating it if needed
+func (w *Writer) page() *paging {
+	if w.paging == nil {
+		w.paging = &paging{page: 1}
+	}
+	return w.paging
+}
+
+// pageFull returns whether the current page holds as many records as set by
+// WithPageSize, so the next record starts a new page
+func (w *Writer) pageFull() bool {
+	return w.paging != nil && w.paging.size > 0 && w.paging.rows >= w.paging.size
+}
+
+// nextPage finishes the current page and starts the next one with the
+// header. If the next page's output can't be opened the writer is left
+// closed.
+func (w *Writer) nextPage() error {
+	p := w.paging
+	w.closed = true
+	w.close()
+	if err := w.finishPage(); err != nil {
+		return err
+	}
+	p.page++
+	p.rows = 0
+	out := w.dest
+	if p.output != nil {
+		next, err := p.output(p.page)
+		if err != nil {
+			err = fmt.Errorf("error opening page %d: %s", p.page, err)
+			w.err = multierror.Append(w.err, err)
+			return err
+		}
+		if w.closeDest && next != w.dest {
+			w.closeOutput()
+		}
+		out = next
+	}
+	var written int64
+	if w.counter != nil {
+		written = w.counter.n
+	}
+	w.bindOutput(out)
+	if w.counter != nil {
+		w.counter.n = written
+	}
+	w.closed = false
+	w.startPage()
+	return nil
+}
+
+// finishPage calls the WithOnPage func for the current page
+func (w *Writer) finishPage() error {
+	p := w.paging
+	if p.onPage == nil {
+		return nil
+	}
+	if err := p.onPage(p.page, w.dest); err != nil {
+		err = fmt.Errorf("error finishing page %d: %s", p.page, err)
+		w.err = multierror.Append(w.err, err)
+		return err
+	}
+	return nil
+}
+
+// startPage resets the format writers for a new page on the current output
+// and writes the header, keeping the writer's counters, stats and errors
+func (w *Writer) startPage() {
+	csvw := csv.NewWriter(w.basew)
+	csvw.Comma = w.csvw.Comma
+	csvw.UseCRLF = w.csvw.UseCRLF
+	w.csvw = csvw
+	w.str.Reset()
+	w.strw = bufio.NewWriterSize(w.basew, w.size)
+	w.table = w.newTable()
+	w.tableOpen, w.tableDone = false, false
+	w.htmlOpen = false
+	w.headerPending = false
+	w.mermaidRows = 0
+	w.jsonOpen, w.jsonClosed, w.jsonRows = false, false, 0
+	w.finished = false
+	w.sinceHeader = 0
+	w.transposed = 0
+	if len(w.columns) > 0 {
+		w.writeHeader()
+	}
+}
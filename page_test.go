@@ -0,0 +1,113 @@
+package multiwriter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPageSize(t *testing.T) {
+	var buf bytes.Buffer
+	var pages []int
+	w := New(&buf, []string{"id"}, JSONFormat, WithPageSize(2), WithOnPage(func(page int, out io.Writer) error {
+		pages = append(pages, page)
+		_, err := fmt.Fprintf(out, "-- page %d --\n", page)
+		return err
+	}))
+	for i := 1; i <= 5; i++ {
+		if err := w.Write([]string{strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// each page is a complete JSON array, finished before the callback
+	want := "[\n{\"id\":\"1\"},\n{\"id\":\"2\"}\n]\n-- page 1 --\n" +
+		"[\n{\"id\":\"3\"},\n{\"id\":\"4\"}\n]\n-- page 2 --\n" +
+		"[\n{\"id\":\"5\"}\n]\n-- page 3 --\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := fmt.Sprint(pages); got != "[1 2 3]" {
+		t.Errorf("got pages %s, want [1 2 3]", got)
+	}
+
+	// a full last page isn't followed by an empty one
+	got := writeRecords(t, []string{"id"}, CSVFormat, [][]string{{"1"}, {"2"}, {"3"}, {"4"}}, WithPageSize(2))
+	if want := "id\n1\n2\nid\n3\n4\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOnPageError(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	w := New(&buf, []string{"id"}, CSVFormat, WithPageSize(2), WithOnPage(func(int, io.Writer) error {
+		calls++
+		return errors.New("quit")
+	}))
+	w.Write([]string{"1"})
+	w.Write([]string{"2"})
+	if err := w.Write([]string{"3"}); err == nil || !strings.Contains(err.Error(), "error finishing page 1: quit") {
+		t.Errorf("got %v, want the page error", err)
+	}
+	if err := w.Write([]string{"4"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v after the page error, want ErrClosed", err)
+	}
+	// Close doesn't finish the page again
+	if err := w.Close(); err == nil || strings.Count(err.Error(), "error finishing page") != 1 {
+		t.Errorf("got %v from Close, want the page error once", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls for the page, want 1", calls)
+	}
+	if got, want := buf.String(), "id\n1\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewPagedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multiwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, closeFn, err := NewPagedFile(filepath.Join(dir, "export-%03d.csv"), 2, []string{"id"}, CSVFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := w.Write([]string{strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := closeFn(); err != nil {
+		t.Fatal(err)
+	}
+	// each file holds a complete output with its own header
+	for page, want := range map[int]string{1: "id\n1\n2\n", 2: "id\n3\n4\n", 3: "id\n5\n"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("export-%03d.csv", page)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got %q in page %d, want %q", got, page, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "export-004.csv")); !os.IsNotExist(err) {
+		t.Errorf("got %v for a fourth page, want no file", err)
+	}
+
+	_, _, err = NewPagedFile(filepath.Join(dir, "missing", "export-%d.csv"), 2, []string{"id"}, CSVFormat)
+	if err == nil || !strings.Contains(err.Error(), "error creating file") {
+		t.Errorf("got %v, want an error creating the file", err)
+	}
+}
@@ -0,0 +1,30 @@
+package multiwriter
+
+import "strings"
+
+// pgCopyEscaper escapes values for the Postgres COPY text format
+var pgCopyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writePGCopy writes the row as a single line of COPY text data
+func (w *Writer) writePGCopy(r row) error {
+	w.renderPGCopy(&w.str, r.raw, r.values)
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderPGCopy renders values into b as tab-separated COPY text data, writing
+// \N for fields whose raw value is the configured null value
+func (w *Writer) renderPGCopy(b *strings.Builder, raw, values []string) {
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString("\t")
+		}
+		if w.nullValue != nil && i < len(raw) && raw[i] == *w.nullValue {
+			b.WriteString(`\N`)
+			continue
+		}
+		b.WriteString(pgCopyEscaper.Replace(v))
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,26 @@
+package multiwriter
+
+import (
+	"testing"
+)
+
+func TestPGCopyEscaping(t *testing.T) {
+	records := [][]string{
+		{"1", "tab\there"},
+		{"2", "two\nlines\r"},
+		{"3", `C:\tmp`},
+		{"4", `\.`},
+		{"5", "NULL"},
+		{"6", ""},
+	}
+	got := writeRecords(t, []string{"id", "note"}, PGCopyFormat, records, WithNullValue("NULL"))
+	want := "1\ttab\\there\n" +
+		"2\ttwo\\nlines\\r\n" +
+		"3\tC:\\\\tmp\n" +
+		"4\t\\\\.\n" +
+		"5\t\\N\n" +
+		"6\t\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
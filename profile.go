@@ -0,0 +1,91 @@
+package multiwriter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// LoaderProfile identifies a data warehouse whose CSV loader quirks the output
+// should accommodate
+type LoaderProfile string
+
+const (
+	// BigQuery produces RFC 4180 CSV with valid UTF-8 and LF newlines inside
+	// quoted fields, for loading with allow_quoted_newlines. NULL, the raw
+	// value \N, is written as an empty unquoted field, the default
+	// null_marker, and an empty string as "".
+	BigQuery LoaderProfile = "bigquery"
+	// Redshift produces pipe-delimited records with backslash-escaped
+	// delimiters and newlines, for COPY with its default DELIMITER '|' and the
+	// ESCAPE option. NULL, the raw value \N, is written as \N, the default
+	// NULL AS, and an empty string as an empty field.
+	Redshift LoaderProfile = "redshift"
+	// Snowflake produces unquoted comma-delimited records with backslash
+	// escape sequences, for the default CSV file format with
+	// ESCAPE_UNENCLOSED_FIELD = '\\'. NULL, the raw value \N, is written as
+	// \N, the default NULL_IF, and an empty string as an empty field, which
+	// loads as an empty string only with EMPTY_FIELD_AS_NULL = FALSE.
+	Snowflake LoaderProfile = "snowflake"
+)
+
+// snowflakeEscaper escapes values for Snowflake's unenclosed field escapes
+var snowflakeEscaper = FuncFormatter(strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	"\n", `\n`,
+	"\r", `\r`,
+).Replace)
+
+// bigQueryQuoter quotes fields as RFC 4180 requires and also quotes empty
+// fields, so BigQuery loads them as empty strings rather than NULL
+var bigQueryQuoter = FuncFormatter(func(field string) string {
+	if field == "" {
+		return `""`
+	}
+	return rfc4180Quoter(",").Format(field)
+})
+
+// loaderProfiles maps each profile to the options it is composed of
+var loaderProfiles = map[LoaderProfile][]Option{
+	BigQuery: {
+		WithValidateUTF8(utf8.RuneError),
+		WithNewline(NewlineLF),
+		WithRawDelimiter(",", "\n"),
+		WithRawEscaper(bigQueryQuoter),
+		WithNullValue(`\N`),
+		WithCSVNullString(""),
+	},
+	Redshift: {
+		WithValidateUTF8(utf8.RuneError),
+		WithNewline(NewlineLF),
+		WithRawDelimiter("|", "\n"),
+		WithNullValue(`\N`),
+		WithCSVNullString(`\N`),
+	},
+	Snowflake: {
+		WithValidateUTF8(utf8.RuneError),
+		WithNewline(NewlineLF),
+		WithRawDelimiter(",", "\n"),
+		WithRawEscaper(snowflakeEscaper),
+		WithNullValue(`\N`),
+		WithCSVNullString(`\N`),
+	},
+}
+
+// WithLoaderProfile applies a preset of CSV options known to import cleanly
+// into the given data warehouse. An unknown profile is reported by Error.
+func WithLoaderProfile(profile LoaderProfile) Option {
+	return func(w *Writer) {
+		opts, ok := loaderProfiles[profile]
+		if !ok {
+			w.err = multierror.Append(w.err, fmt.Errorf("unknown loader profile %q", profile))
+			return
+		}
+		for _, o := range opts {
+			o(w)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package multiwriter
+
+import (
+	"bytes"
+	"testing"
+)
+
+// writeProfile writes the test records with the given loader profile and
+// returns the output
+func writeProfile(t *testing.T, profile LoaderProfile) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "note"}, CSVFormat, WithLoaderProfile(profile))
+	records := [][]string{
+		{"1", `\N`},
+		{"2", ""},
+		{"3", "a,b|c"},
+		{"4", `say "hi"`},
+		{"5", "line1\nline2"},
+		{"6", `C:\tmp`},
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestLoaderProfileBigQuery(t *testing.T) {
+	// NULL is the default null_marker, an empty unquoted field, while empty
+	// strings and fields with commas, quotes or newlines are quoted
+	want := "id,note\n" +
+		"1,\n" +
+		"2,\"\"\n" +
+		"3,\"a,b|c\"\n" +
+		"4,\"say \"\"hi\"\"\"\n" +
+		"5,\"line1\nline2\"\n" +
+		"6,C:\\tmp\n"
+	if got := writeProfile(t, BigQuery); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoaderProfileRedshift(t *testing.T) {
+	// NULL is written as the default NULL AS '\N' and the delimiter, newline
+	// and backslash are escaped as COPY's ESCAPE option expects
+	want := "id|note\n" +
+		"1|\\N\n" +
+		"2|\n" +
+		"3|a,b\\|c\n" +
+		"4|say \"hi\"\n" +
+		"5|line1\\\nline2\n" +
+		"6|C:\\\\tmp\n"
+	if got := writeProfile(t, Redshift); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoaderProfileSnowflake(t *testing.T) {
+	// NULL is written as the default NULL_IF '\N' and unenclosed fields use
+	// ESCAPE_UNENCLOSED_FIELD's backslash sequences
+	want := "id,note\n" +
+		"1,\\N\n" +
+		"2,\n" +
+		"3,a\\,b|c\n" +
+		"4,say \"hi\"\n" +
+		"5,line1\\nline2\n" +
+		"6,C:\\\\tmp\n"
+	if got := writeProfile(t, Snowflake); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
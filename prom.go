@@ -0,0 +1,62 @@
+package multiwriter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// promEscaper escapes label values for the Prometheus text exposition format
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promMetric describes how records map to Prometheus samples
+type promMetric struct {
+	name      string
+	valueCol  string
+	labelCols []string
+}
+
+// writeProm writes the row as a single sample in the Prometheus text
+// exposition format
+func (w *Writer) writeProm(r row) error {
+	if err := w.renderProm(&w.str, r.values); err != nil {
+		w.str.Reset()
+		return err
+	}
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderProm renders values into b as a single sample in the Prometheus text
+// exposition format, e.g. name{label="v"} 1.5
+func (w *Writer) renderProm(b *strings.Builder, values []string) error {
+	if w.prom == nil {
+		return fmt.Errorf("no metric configured, see WithPromMetric")
+	}
+	vi := w.columnIndex(w.prom.valueCol)
+	if vi < 0 {
+		return fmt.Errorf("unknown value column %q", w.prom.valueCol)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(values[vi]), 64)
+	if err != nil {
+		return fmt.Errorf("non-numeric value %q in column %q", values[vi], w.prom.valueCol)
+	}
+	b.WriteString(w.prom.name)
+	if len(w.prom.labelCols) > 0 {
+		b.WriteString("{")
+		for i, col := range w.prom.labelCols {
+			li := w.columnIndex(col)
+			if li < 0 {
+				return fmt.Errorf("unknown label column %q", col)
+			}
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(fmt.Sprintf("%s=\"%s\"", col, promEscaper.Replace(values[li])))
+		}
+		b.WriteString("}")
+	}
+	b.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+	return nil
+}
@@ -0,0 +1,35 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPromExposition(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"path", "code", "hits"}, PromFormat,
+		WithPromMetric("http_requests_total", "hits", "path", "code"))
+	for _, r := range [][]string{{"/a\"b\\c\nd", "200", "1.5e3"}, {"/", "500", " 7 "}} {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Write([]string{"/", "500", "x"})
+	if err == nil || !strings.Contains(err.Error(), `non-numeric value "x" in column "hits"`) {
+		t.Errorf("got %v, want a non-numeric value error", err)
+	}
+	w.Close()
+	want := `http_requests_total{path="/a\"b\\c\nd",code="200"} 1500` + "\n" +
+		`http_requests_total{path="/",code="500"} 7` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPromWithoutLabels(t *testing.T) {
+	got := writeRecords(t, []string{"value"}, PromFormat, [][]string{{"0.25"}}, WithPromMetric("up", "value"))
+	if want := "up 0.25\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
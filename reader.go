@@ -0,0 +1,209 @@
+package multiwriter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader reads records back from output written in the CSV or Text format
+type Reader struct {
+	format  string
+	csvr    *csv.Reader
+	textr   *bufio.Reader
+	line    int
+	columns []string
+	// header is set once the columns have been read, and next holds the
+	// first Text record, which is read along with the columns
+	header  bool
+	next    []string
+	nextErr error
+	started bool
+	done    bool
+}
+
+// NewReader returns a new Reader that reads records in format from r. For the
+// CSV and TSV formats the first record is the header. For the Text format the
+// columns are the keys of the first block and anything before it, such as the
+// header written by WithTextHeader, is skipped.
+func NewReader(r io.Reader, format string) *Reader {
+	reader := &Reader{format: format}
+	switch format {
+	case CSVFormat:
+		reader.csvr = csv.NewReader(r)
+	case TSVFormat:
+		reader.format = CSVFormat
+		reader.csvr = csv.NewReader(r)
+		reader.csvr.Comma = '\t'
+	case TextFormat:
+		reader.textr = bufio.NewReader(r)
+	}
+	return reader
+}
+
+// Columns returns the column names read from the header, reading it if no
+// record has been read yet. It returns nil if the header can't be read.
+func (r *Reader) Columns() []string {
+	r.readHeader()
+	return r.columns
+}
+
+// Read returns the next record, or io.EOF once all records have been read
+func (r *Reader) Read() ([]string, error) {
+	r.readHeader()
+	if r.next != nil || r.nextErr != nil {
+		record, err := r.next, r.nextErr
+		r.next, r.nextErr = nil, nil
+		return record, err
+	}
+	switch r.format {
+	case CSVFormat:
+		return r.csvr.Read()
+	case TextFormat:
+		return r.readBlock()
+	}
+	return nil, fmt.Errorf("unsupported format %q", r.format)
+}
+
+// ReadAll reads the columns and all the remaining records
+func (r *Reader) ReadAll() ([]string, [][]string, error) {
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return r.columns, records, nil
+		}
+		if err != nil {
+			return r.columns, records, err
+		}
+		records = append(records, record)
+	}
+}
+
+// Decode reads the columns and records of output written in format from r, as
+// read by a Reader
+func Decode(r io.Reader, format string) ([]string, [][]string, error) {
+	return NewReader(r, format).ReadAll()
+}
+
+// readHeader reads the columns if they haven't been read yet. For the Text
+// format this reads the first record, which is returned by the next Read.
+func (r *Reader) readHeader() {
+	if r.header {
+		return
+	}
+	r.header = true
+	switch r.format {
+	case CSVFormat:
+		r.columns, r.nextErr = r.csvr.Read()
+	case TextFormat:
+		r.next, r.nextErr = r.readBlock()
+	default:
+		r.nextErr = fmt.Errorf("unsupported format %q", r.format)
+	}
+}
+
+// readBlock reads the next --- delimited block of key: value lines as a
+// record ordered by the columns, taking the columns from the block if they are
+// not known yet. Values quoted by WithQuotedTextValues are unquoted.
+func (r *Reader) readBlock() ([]string, error) {
+	for !r.started {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		r.started = line == "---"
+	}
+	var keys, values []string
+	for {
+		line, err := r.readLine()
+		if err == io.EOF && keys != nil {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == "---" {
+			break
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing ':' in text block: %q", r.line, line)
+		}
+		key, value := line[:i], strings.TrimPrefix(line[i+1:], " ")
+		if key == "columns" && r.isTextHeader(value, keys) {
+			// a header repeated by WithRepeatHeaderEvery
+			continue
+		}
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	if r.columns == nil {
+		r.columns = keys
+		return values, nil
+	}
+	record := make([]string, len(r.columns))
+	for i, key := range keys {
+		j := indexOf(r.columns, key)
+		if j < 0 {
+			return nil, fmt.Errorf("line %d: unknown column %q in text block", r.line, key)
+		}
+		record[j] = values[i]
+	}
+	return record, nil
+}
+
+// isTextHeader returns whether value lists the columns, or the keys read so
+// far if the columns are not known yet, as rendered in a Text format header
+func (r *Reader) isTextHeader(value string, keys []string) bool {
+	columns := r.columns
+	if columns == nil {
+		columns = keys
+	}
+	parts := strings.Split(value, ", ")
+	if len(parts) != len(columns) {
+		return false
+	}
+	for i, part := range parts {
+		if part != columns[i] && !strings.HasPrefix(part, columns[i]+" (") {
+			return false
+		}
+	}
+	return true
+}
+
+// readLine reads the next line without its line ending
+func (r *Reader) readLine() (string, error) {
+	if r.done {
+		return "", io.EOF
+	}
+	line, err := r.textr.ReadString('\n')
+	if err == io.EOF {
+		r.done = true
+		if line == "" {
+			return "", io.EOF
+		}
+	} else if err != nil {
+		return "", err
+	}
+	r.line++
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// indexOf returns the index of column in columns, or -1
+func indexOf(columns []string, column string) int {
+	for i, col := range columns {
+		if col == column {
+			return i
+		}
+	}
+	return -1
+}
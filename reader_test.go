@@ -0,0 +1,111 @@
+package multiwriter
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderRoundTrip(t *testing.T) {
+	columns := []string{"id", "note"}
+	records := [][]string{
+		{"1", "plain"},
+		{"2", "a, b"},
+		{"3", `say "hi"`},
+		{"4", "key: value"},
+		{"5", "two\nlines"},
+		{"6", ""},
+	}
+	tests := []struct {
+		format string
+		opts   []Option
+	}{
+		{CSVFormat, nil},
+		{TSVFormat, nil},
+		{TextFormat, []Option{WithQuotedTextValues(true)}},
+		{TextFormat, []Option{WithQuotedTextValues(true), WithTextHeader(true), WithRepeatHeaderEvery(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := writeRecords(t, columns, tt.format, records, tt.opts...)
+			r := NewReader(strings.NewReader(out), tt.format)
+			if got := r.Columns(); !reflect.DeepEqual(got, columns) {
+				t.Errorf("got columns %q, want %q", got, columns)
+			}
+			var got [][]string
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, records) {
+				t.Errorf("got %q, want %q from %q", got, records, out)
+			}
+		})
+	}
+}
+
+func TestReaderUnsupportedFormat(t *testing.T) {
+	r := NewReader(strings.NewReader("[]"), JSONFormat)
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), `unsupported format "json"`) {
+		t.Errorf("got %v, want an unsupported format error", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		in      string
+		columns []string
+		records [][]string
+		err     string
+	}{
+		{"csv", CSVFormat, "id,name\n1,a\n2,b\n", []string{"id", "name"}, [][]string{{"1", "a"}, {"2", "b"}}, ""},
+		{"text", TextFormat, "---\nid: 1\nname: a\n---\nid: 2\nname: b\n", []string{"id", "name"}, [][]string{{"1", "a"}, {"2", "b"}}, ""},
+		{"empty", CSVFormat, "", nil, nil, ""},
+		{"header only", CSVFormat, "id,name\n", []string{"id", "name"}, nil, ""},
+		// the records read before an error are returned with it
+		{"short record", CSVFormat, "id,name\n1,a\n2\n", []string{"id", "name"}, [][]string{{"1", "a"}}, "wrong number of fields"},
+		{"bad text", TextFormat, "---\nid: 1\nname: a\n---\nid: 2\nbogus\n", []string{"id", "name"}, [][]string{{"1", "a"}}, `line 6: missing ':' in text block: "bogus"`},
+		{"unsupported", JSONFormat, "[]", nil, nil, `unsupported format "json"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, records, err := Decode(strings.NewReader(tt.in), tt.format)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("got columns %q, want %q", columns, tt.columns)
+			}
+			if !reflect.DeepEqual(records, tt.records) {
+				t.Errorf("got %q, want %q", records, tt.records)
+			}
+		})
+	}
+}
+
+func TestReadAllAfterRead(t *testing.T) {
+	r := NewReader(strings.NewReader("id\n1\n2\n3\n"), CSVFormat)
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	// ReadAll returns the remaining records along with the columns
+	columns, records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("got columns %q, want %q", columns, want)
+	}
+	if want := [][]string{{"2"}, {"3"}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q, want %q", records, want)
+	}
+}
@@ -0,0 +1,59 @@
+package multiwriter
+
+import (
+	"io"
+	"sync"
+)
+
+// Format is an output format added with Register. A Writer creates its own
+// Format and calls its methods with the output, which is buffered and flushed
+// by the Writer. Values are passed after formatting, so options such as
+// formatters and projections apply as for the built-in formats.
+type Format interface {
+	// WriteHeader writes the header, unless it is omitted with WithNoHeader
+	WriteHeader(out io.Writer, columns []string) error
+	// WriteRow writes a record
+	WriteRow(out io.Writer, values []string) error
+	// Flush writes anything the format holds back, e.g. a document trailer,
+	// every time the Writer flushes
+	Flush(out io.Writer) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() Format{}
+)
+
+// Register makes a format available to New under name, with newFormat
+// creating the Format of each Writer. It panics if name is already registered
+// or is one of AllFormats.
+func Register(name string, newFormat func() Format) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if builtinFormat(name) {
+		panic("multiwriter: cannot override built-in format " + name)
+	}
+	if _, ok := registry[name]; ok {
+		panic("multiwriter: Register called twice for format " + name)
+	}
+	registry[name] = newFormat
+}
+
+// registeredFormat returns the constructor of the format registered under
+// name, if any
+func registeredFormat(name string) (func() Format, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	newFormat, ok := registry[name]
+	return newFormat, ok
+}
+
+// builtinFormat returns whether format is one of AllFormats
+func builtinFormat(format string) bool {
+	for _, f := range AllFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
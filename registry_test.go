@@ -0,0 +1,101 @@
+package multiwriter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// nopFormat is a Format that writes nothing
+type nopFormat struct{}
+
+func (nopFormat) WriteHeader(io.Writer, []string) error { return nil }
+func (nopFormat) WriteRow(io.Writer, []string) error    { return nil }
+func (nopFormat) Flush(io.Writer) error                 { return nil }
+
+func TestRegisterPanics(t *testing.T) {
+	newFormat := func() Format { return nopFormat{} }
+	Register("test-nop", newFormat)
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test-nop")
+		registryMu.Unlock()
+	}()
+	tests := []struct {
+		name, want string
+	}{
+		{"test-nop", "multiwriter: Register called twice for format test-nop"},
+		{CSVFormat, "multiwriter: cannot override built-in format csv"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if got := recover(); got != tt.want {
+					t.Errorf("got panic %v registering %s, want %q", got, tt.name, tt.want)
+				}
+			}()
+			Register(tt.name, newFormat)
+		}()
+	}
+}
+
+// pipeFormat writes pipe-separated rows and a trailer on every flush, failing
+// rows holding "FAIL"
+type pipeFormat struct {
+	rows int
+}
+
+func (f *pipeFormat) WriteHeader(out io.Writer, columns []string) error {
+	_, err := fmt.Fprintf(out, "# %s\n", strings.Join(columns, "|"))
+	return err
+}
+
+func (f *pipeFormat) WriteRow(out io.Writer, values []string) error {
+	if values[len(values)-1] == "FAIL" {
+		return errors.New("bad row")
+	}
+	f.rows++
+	_, err := fmt.Fprintln(out, strings.Join(values, "|"))
+	return err
+}
+
+func (f *pipeFormat) Flush(out io.Writer) error {
+	_, err := fmt.Fprintf(out, "-- %d rows\n", f.rows)
+	return err
+}
+
+func TestRegisteredFormat(t *testing.T) {
+	Register("test-pipe", func() Format { return &pipeFormat{} })
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test-pipe")
+		registryMu.Unlock()
+	}()
+
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name", "email"}, "test-pipe",
+		WithColumns([]string{"id", "name"}),
+		WithHeaderLabels(map[string]string{"name": "Name"}),
+		WithFormatter("name", FuncFormatter(strings.ToUpper)))
+	w.Write([]string{"1", "alice", "a@example.com"})
+	w.Flush()
+	w.Write([]string{"2", "bob", "b@example.com"})
+	if err := w.Write([]string{"3", "fail", "c@example.com"}); err == nil || !strings.Contains(err.Error(), "error writing record to test-pipe: bad row") {
+		t.Errorf("got %v, want the row error", err)
+	}
+	w.Close()
+	// each writer creates its own format, and values are formatted and
+	// projected as for the built-in formats
+	want := "# id|Name\n1|ALICE\n-- 1 rows\n2|BOB\n-- 2 rows\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got := writeRecords(t, []string{"id"}, "test-pipe", [][]string{{"1"}}, WithNoHeader())
+	if want := "1\n-- 1 rows\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package multiwriter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// ResetBuffers discards all buffered records and output that has not been
+// flushed yet, and clears row counters, stats and errors, while keeping the
+// columns, format and options. This allows a configured writer to be reused
+// for independent batches to the same output.
+func (w *Writer) ResetBuffers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.resetBuffers()
+}
+
+// resetBuffers discards buffered records and output and clears the writer's
+// state while holding the lock
+func (w *Writer) resetBuffers() {
+	w.rows = nil
+	w.str.Reset()
+	w.strw.Reset(w.basew)
+	csvw := csv.NewWriter(w.basew)
+	csvw.Comma = w.csvw.Comma
+	csvw.UseCRLF = w.csvw.UseCRLF
+	w.csvw = csvw
+	w.table.ClearRows()
+	w.table.ClearFooter()
+	if w.sink != nil {
+		w.sink.buf.Reset()
+	}
+	w.index = 0
+	w.rowCount = 0
+	w.sinceHeader = 0
+	w.transposed = 0
+	w.pending = 0
+	w.pendingRows = 0
+	w.jsonOpen, w.jsonClosed, w.jsonRows = false, false, 0
+	w.htmlOpen = false
+	w.mermaidOpen, w.mermaidRows = false, 0
+	if w.run != nil {
+		w.run = &runLength{}
+	}
+	if w.reduce != nil {
+		w.reduce.acc, w.reduce.n = nil, 0
+	}
+	w.stats = map[string]*Stats{}
+	if w.footerAggs != nil {
+		w.footerValues = map[string][]string{}
+	}
+	w.total = nil
+	w.aborted = nil
+	w.err = nil
+	w.recordErrs = nil
+	w.failed = 0
+}
+
+// Reset flushes the writer and reuses it for records with different columns
+// on the same output, e.g. the next result set in a REPL. Errors are cleared,
+// the format writers start afresh and the header for the new columns is
+// written unless WithNoHeader is set. Formatters are removed since they were
+// registered for the old columns, and so is the projection set with
+// WithColumns or WithExcludeColumns. If the columns are locked and would
+// change, the writer is left unchanged and the error is reported by Error.
+func (w *Writer) Reset(columns []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	if err := w.setColumns(columns); err != nil {
+		return
+	}
+	if err := w.closeJSON(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error closing json: %s", err))
+	}
+	if err := w.closeHTML(); err != nil {
+		w.err = multierror.Append(w.err, fmt.Errorf("error closing html: %s", err))
+	}
+	w.flushOutput()
+	w.clearColumnOptions()
+	w.restart()
+}
+
+// ResetOutput finishes the current output as Close does and reuses the writer
+// for out, e.g. to render many small reports in a loop with one writer. If
+// columns is nil the columns, formatters and projection are kept, otherwise
+// the columns are replaced as with Reset. Buffered records, errors and
+// counters are cleared and the header is written to out unless WithNoHeader
+// is set. The error of the finished output is returned, as by Close. If the
+// columns are locked and would change, the writer is left unchanged and the
+// error is returned.
+func (w *Writer) ResetOutput(out io.Writer, columns []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if columns != nil && w.lockCols && !equalColumns(w.columns, columns) {
+		err := fmt.Errorf("%w: cannot change %v to %v", ErrColumnsLocked, w.columns, columns)
+		w.err = multierror.Append(w.err, err)
+		return err
+	}
+	if !w.done {
+		w.closed = true
+		w.close()
+	}
+	err := w.err
+	if columns != nil {
+		w.columns = columns
+		w.clearColumnOptions()
+	}
+	if w.sink != nil && out != io.Writer(w.sink) {
+		w.sink = nil
+	}
+	if w.closeDest && out != w.dest {
+		w.closeOutput()
+		err = w.err
+	}
+	w.bindOutput(out)
+	w.closed, w.done = false, false
+	w.start = time.Now()
+	w.restart()
+	return err
+}
+
+// clearColumnOptions removes the formatters and projection, which refer to
+// the writer's columns
+func (w *Writer) clearColumnOptions() {
+	w.formatters = map[string][]Formatter{}
+	w.formattersAt = nil
+	w.caches = map[string]*lruCache{}
+	w.output, w.exclude, w.project, w.inputColumns = nil, nil, nil, nil
+}
+
+// restart clears the writer's state and format writers after a reset and
+// writes the header for its columns
+func (w *Writer) restart() {
+	w.resetBuffers()
+	w.table = w.newTable()
+	w.strw = bufio.NewWriterSize(w.basew, w.size)
+	w.tableOpen, w.tableDone = false, false
+	w.headerPending = false
+	w.columnsFixed = false
+	w.finished = false
+	if len(w.columns) > 0 && !w.dynamic {
+		w.writeHeader()
+	}
+}
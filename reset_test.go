@@ -0,0 +1,163 @@
+package multiwriter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResetBuffersStreamed(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{JSONFormat, "[\n{\"id\":\"2\"}\n]\n"},
+		{NDJSONFormat, "{\"id\":\"2\"}\n"},
+		{HTMLFormat, "<table>\n<tbody>\n<tr><td>2</td></tr>\n</tbody>\n</table>\n"},
+		{MermaidFormat, "```mermaid\nblock-beta\n  columns 1\n  hc0[\"id\"]\n  r0c0[\"2\"]\n```\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id"}, tt.format)
+			if err := w.Write([]string{"1"}); err != nil {
+				t.Fatal(err)
+			}
+			w.ResetBuffers()
+			if err := w.Write([]string{"2"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSVFormat, "id\nA\nid,name\nb,c\n"},
+		{JSONFormat, "[\n{\"id\":\"A\"}\n]\n[\n{\"id\":\"b\",\"name\":\"c\"}\n]\n"},
+		{TableFormat, "+----+\n| ID |\n+----+\n| A  |\n+----+\n+----+------+\n| ID | NAME |\n+----+------+\n| b  | c    |\n+----+------+\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := New(&buf, []string{"id"}, tt.format, WithFormatter("id", FuncFormatter(strings.ToUpper)))
+			if err := w.Write([]string{"a"}); err != nil {
+				t.Fatal(err)
+			}
+			// the old records are finished and the formatters for the old
+			// columns removed
+			w.Reset([]string{"id", "name"})
+			if err := w.Write([]string{"b", "c"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetLockedColumns(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, CSVFormat, WithLockColumns(), WithFormatter("id", FuncFormatter(strings.ToUpper)))
+	w.Write([]string{"a"})
+	w.Reset([]string{"id", "name"})
+	if !errors.Is(w.Error(), ErrColumnsLocked) {
+		t.Errorf("got %v, want ErrColumnsLocked", w.Error())
+	}
+	// the writer is left unchanged, formatters included
+	if err := w.Write([]string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, want := buf.String(), "id\nA\nB\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestResetOutput(t *testing.T) {
+	// the finished output's error is returned and cleared, while the
+	// formatters are kept for the same columns
+	w := New(&failingWriter{}, []string{"id"}, JSONFormat, WithFormatter("id", FuncFormatter(strings.ToUpper)))
+	w.Write([]string{"a"})
+	var buf bytes.Buffer
+	if err := w.ResetOutput(&buf, nil); err == nil || !strings.Contains(err.Error(), "error flushing json: write failed") {
+		t.Errorf("got %v, want the error of the finished output", err)
+	}
+	if err := w.Write([]string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "[\n{\"id\":\"B\"}\n]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// new columns drop the old formatters
+	var first, second bytes.Buffer
+	w = New(&first, []string{"id"}, CSVFormat, WithFormatter("id", FuncFormatter(strings.ToUpper)))
+	w.Write([]string{"a"})
+	if err := w.ResetOutput(&second, []string{"id", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]string{"b", "c"})
+	w.Close()
+	if got, want := first.String(), "id\nA\n"; got != want {
+		t.Errorf("got %q in the first output, want %q", got, want)
+	}
+	if got, want := second.String(), "id,name\nb,c\n"; got != want {
+		t.Errorf("got %q in the second output, want %q", got, want)
+	}
+}
+
+func TestResetOutputClosesUnderlying(t *testing.T) {
+	first, second := &closeCounter{}, &closeCounter{}
+	w := New(first, []string{"id"}, CSVFormat, WithCloseUnderlying())
+	w.Write([]string{"1"})
+	if err := w.ResetOutput(second, nil); err != nil {
+		t.Fatal(err)
+	}
+	if first.closes != 1 {
+		t.Errorf("got %d closes of the finished output, want 1", first.closes)
+	}
+	w.Write([]string{"2"})
+	w.Close()
+	if second.closes != 1 {
+		t.Errorf("got %d closes of the new output, want 1", second.closes)
+	}
+	if got, want := first.String()+second.String(), "id\n1\nid\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestResetOutputLockedColumns(t *testing.T) {
+	var first, second bytes.Buffer
+	w := New(&first, []string{"id"}, CSVFormat, WithLockColumns())
+	w.Write([]string{"1"})
+	if err := w.ResetOutput(&second, []string{"id", "name"}); !errors.Is(err, ErrColumnsLocked) {
+		t.Errorf("got %v, want ErrColumnsLocked", err)
+	}
+	// the writer keeps writing to the first output
+	w.Write([]string{"2"})
+	w.Close()
+	if got, want := first.String(), "id\n1\n2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if second.Len() != 0 {
+		t.Errorf("got %q in the second output, want nothing", second.String())
+	}
+}
@@ -0,0 +1,36 @@
+package multiwriter
+
+import "strconv"
+
+// runLength tracks the current run of identical records
+type runLength struct {
+	raw   []string
+	extra appendedValues
+	count int
+}
+
+// countRun adds the record to the current run if it is identical, otherwise
+// writes the finished run and starts a new one
+func (w *Writer) countRun(record []string, extra appendedValues) error {
+	if w.run.count > 0 && equalColumns(w.run.raw, record) && w.run.extra == extra {
+		w.run.count++
+		return nil
+	}
+	err := w.endRun()
+	w.run.raw = append([]string(nil), record...)
+	w.run.extra = extra
+	w.run.count = 1
+	return err
+}
+
+// endRun writes the current run, if any, as a single record with its count
+func (w *Writer) endRun() error {
+	if w.run == nil || w.run.count == 0 {
+		return nil
+	}
+	extra := w.run.extra
+	extra.count = strconv.Itoa(w.run.count)
+	record := w.appendValues(w.run.raw, extra)
+	w.run.raw, w.run.extra, w.run.count = nil, appendedValues{}, 0
+	return w.writeRecord(record)
+}
@@ -0,0 +1,15 @@
+package multiwriter
+
+import (
+	"testing"
+)
+
+func TestRunLengthCount(t *testing.T) {
+	records := [][]string{{"a"}, {"a"}, {"b"}, {"a"}, {"c"}, {"c"}, {"c"}}
+	got := writeRecords(t, []string{"v"}, CSVFormat, records, WithRunLengthCount("n"))
+	// only consecutive records are collapsed and the last run is written
+	// on Close
+	if want := "v,n\na,2\nb,1\na,1\nc,3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package multiwriter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortOrder is the direction in which rows are sorted
+type SortOrder int
+
+const (
+	// Ascending sorts rows from the smallest to the largest value
+	Ascending SortOrder = iota
+	// Descending sorts rows from the largest to the smallest value
+	Descending
+)
+
+// sorter describes how buffered rows are sorted before they are written
+type sorter struct {
+	column string
+	order  SortOrder
+	less   func(a, b []string) bool
+}
+
+// WithSort buffers records until Flush and emits them sorted by column in the
+// given order. Columns declared as Int or Float are compared as numbers, with
+// values that don't parse greater than any number, and other columns as
+// strings. Records with equal values keep the order they were written in.
+func WithSort(column string, order SortOrder) Option {
+	return func(w *Writer) {
+		w.sort = &sorter{column: column, order: order}
+	}
+}
+
+// WithSortFunc buffers records until Flush and emits them sorted by less,
+// which reports whether raw record a sorts before raw record b. Records for
+// which less is false both ways keep the order they were written in.
+func WithSortFunc(less func(a, b []string) bool) Option {
+	return func(w *Writer) {
+		w.sort = &sorter{less: less}
+	}
+}
+
+// NumericLess returns a less function for WithSortFunc that compares the
+// field at index as numbers, with fields that don't parse greater than any
+// number
+func NumericLess(index int) func(a, b []string) bool {
+	return func(a, b []string) bool {
+		return lessNumbers(a[index], b[index])
+	}
+}
+
+// sortRows stably sorts rows, leaving them unchanged if the sort column is
+// unknown
+func (w *Writer) sortRows(rows []row) {
+	less := w.sort.less
+	if less == nil {
+		i := w.columnIndex(w.sort.column)
+		if i < 0 {
+			return
+		}
+		numeric := w.numericColumn(w.sort.column)
+		less = func(a, b []string) bool {
+			if numeric {
+				return lessNumbers(a[i], b[i])
+			}
+			return a[i] < b[i]
+		}
+		if w.sort.order == Descending {
+			asc := less
+			less = func(a, b []string) bool {
+				return asc(b, a)
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].separator || rows[j].separator {
+			return false
+		}
+		return less(rows[i].raw, rows[j].raw)
+	})
+}
+
+// lessNumbers compares a and b as numbers, sorting values that don't parse
+// after those that do
+func lessNumbers(a, b string) bool {
+	x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if errA != nil || errB != nil {
+		return errA == nil && errB != nil
+	}
+	return x < y
+}
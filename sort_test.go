@@ -0,0 +1,51 @@
+package multiwriter
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	records := [][]string{{"b", "10"}, {"a", "9"}, {"c", "n/a"}, {"a", "10"}}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		// equal names keep the order they were written in
+		{"string", []Option{WithSort("name", Ascending)}, "name,n\na,9\na,10\nb,10\nc,n/a\n"},
+		{"string descending", []Option{WithSort("name", Descending)}, "name,n\nc,n/a\nb,10\na,9\na,10\n"},
+		// without a type, numbers are compared as strings
+		{"untyped", []Option{WithSort("n", Ascending)}, "name,n\nb,10\na,10\na,9\nc,n/a\n"},
+		// values that don't parse sort after any number
+		{"int", []Option{WithColumnType("n", Int), WithSort("n", Ascending)}, "name,n\na,9\nb,10\na,10\nc,n/a\n"},
+		{"int descending", []Option{WithColumnType("n", Int), WithSort("n", Descending)}, "name,n\nc,n/a\nb,10\na,10\na,9\n"},
+		{"unknown column", []Option{WithSort("missing", Ascending)}, "name,n\nb,10\na,9\nc,n/a\na,10\n"},
+		{"func", []Option{WithSortFunc(NumericLess(1))}, "name,n\na,9\nb,10\na,10\nc,n/a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeRecords(t, []string{"name", "n"}, CSVFormat, records, tt.opts...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortPerFlush(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name"}, CSVFormat, WithSort("name", Ascending))
+	for _, batch := range [][]string{{"c", "b"}, {"a"}} {
+		for _, name := range batch {
+			if err := w.Write([]string{name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Flush()
+	}
+	w.Close()
+	// rows are sorted within each flush, not across flushes
+	if got, want := buf.String(), "name\nb\nc\na\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package multiwriter
+
+import (
+	"database/sql"
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// WriteRows writes every row of rows as a record and returns the first error
+// encountered, leaving rows positioned after the failing row. If the writer
+// was created without columns, they are taken from rows.Columns, ahead of the
+// columns the writer appends itself, and the header is written before the
+// first record as it would be by New. NULL values are written as the value set
+// with WithNullValue, or as an empty string.
+func (w *Writer) WriteRows(rows *sql.Rows) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return w.rowsError(err)
+	}
+	w.mu.Lock()
+	if len(w.columns) == w.appendedColumns() && w.output == nil {
+		if err := w.setColumns(append(cols[:len(cols):len(cols)], w.columns...)); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		w.table = w.newTable()
+		if w.headerState == nil && !w.dynamic {
+			w.writeHeader()
+		}
+	}
+	w.mu.Unlock()
+	raw := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return w.rowsError(err)
+		}
+		record := make([]string, len(raw))
+		for i, v := range raw {
+			switch {
+			case v != nil:
+				record[i] = string(v)
+			case w.nullValue != nil:
+				record[i] = *w.nullValue
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return w.rowsError(err)
+	}
+	return nil
+}
+
+// rowsError aggregates an error reading from sql.Rows and returns it
+func (w *Writer) rowsError(err error) error {
+	err = fmt.Errorf("error reading rows: %s", err)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = multierror.Append(w.err, err)
+	return err
+}
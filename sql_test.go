@@ -0,0 +1,68 @@
+package multiwriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWriteRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+		AddRow("1", "alice").
+		AddRow("2", nil))
+	rows, err := db.Query("SELECT id, name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	w := New(&buf, nil, CSVFormat, WithNullValue("NULL"))
+	if err := w.WriteRows(rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id,name\n1,alice\n2,NULL\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteRowsDynamicColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+	rows, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	w := New(&buf, nil, CSVFormat, WithDynamicColumns())
+	if err := w.WriteRows(rows); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("got %q before the first flush, want the header deferred", buf.String())
+	}
+	if err := w.AddColumn("name", ColumnDefault("n/a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id,name\n1,n/a\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package multiwriter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Stats summarizes the raw values written to a column. Sum, Min and Max only
+// account for values that parse as numbers.
+type Stats struct {
+	// Count is the number of values written
+	Count int
+	// Numeric is the number of values that parsed as numbers
+	Numeric int
+	Sum     float64
+	Min     float64
+	Max     float64
+
+	values []float64
+}
+
+// Mean returns the average of the numeric values, or 0 if there were none
+func (s Stats) Mean() float64 {
+	if s.Numeric == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Numeric)
+}
+
+// add accounts for value in the stats, keeping numeric values for Histogram
+// if keep is set
+func (s *Stats) add(value string, keep bool) {
+	s.Count++
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return
+	}
+	if s.Numeric == 0 || f < s.Min {
+		s.Min = f
+	}
+	if s.Numeric == 0 || f > s.Max {
+		s.Max = f
+	}
+	s.Numeric++
+	s.Sum += f
+	if keep {
+		s.values = append(s.values, f)
+	}
+}
+
+// statsAgg returns the func computing agg from a column's stats if agg is
+// one of the built-in aggregations, or nil
+func statsAgg(agg AggFunc) func(Stats) float64 {
+	switch reflect.ValueOf(agg).Pointer() {
+	case reflect.ValueOf(Sum).Pointer():
+		return func(s Stats) float64 { return s.Sum }
+	case reflect.ValueOf(Avg).Pointer():
+		return Stats.Mean
+	case reflect.ValueOf(Min).Pointer():
+		return func(s Stats) float64 { return s.Min }
+	case reflect.ValueOf(Max).Pointer():
+		return func(s Stats) float64 { return s.Max }
+	case reflect.ValueOf(Count).Pointer():
+		return func(s Stats) float64 { return float64(s.Numeric) }
+	}
+	return nil
+}
+
+// collectingStats returns whether column stats need to be collected
+func (w *Writer) collectingStats() bool {
+	return w.withStats || w.footerFunc != nil || w.footerAggs != nil || w.footerStats != nil
+}
+
+// collectStats accounts for the raw record in the column stats
+func (w *Writer) collectStats(record []string) {
+	w.rowCount++
+	for i, val := range record {
+		if i >= len(w.columns) {
+			break
+		}
+		s, ok := w.stats[w.columns[i]]
+		if !ok {
+			s = &Stats{}
+			w.stats[w.columns[i]] = s
+		}
+		s.add(val, w.withStats)
+		if _, ok := w.footerAggs[w.columns[i]]; ok {
+			w.footerValues[w.columns[i]] = append(w.footerValues[w.columns[i]], val)
+		}
+	}
+}
+
+// statsSnapshot returns a copy of the column stats collected so far
+func (w *Writer) statsSnapshot() map[string]Stats {
+	stats := make(map[string]Stats, len(w.stats))
+	for col, s := range w.stats {
+		stats[col] = *s
+	}
+	return stats
+}
+
+// footer computes the footer with the footer func and column aggregations,
+// if set
+func (w *Writer) footer() []string {
+	var footer []string
+	if w.footerFunc != nil {
+		footer = w.footerFunc(w.rowCount, w.statsSnapshot())
+	}
+	if w.footerAggs == nil && w.footerStats == nil {
+		return footer
+	}
+	cells := make([]string, len(w.columns))
+	copy(cells, footer)
+	for i, col := range w.columns {
+		if agg, ok := w.footerAggs[col]; ok {
+			cells[i] = agg(w.footerValues[col])
+		}
+		if agg, ok := w.footerStats[col]; ok {
+			var s Stats
+			if cs, ok := w.stats[col]; ok {
+				s = *cs
+			}
+			cells[i] = formatNumber(agg(s))
+		}
+	}
+	return cells
+}
+
+// Histogram returns the distribution of the numeric values written to column
+// across the given number of equal-width buckets between the column's minimum
+// and maximum. Buckets are keyed by their range, e.g. "[0, 2.5)", and the
+// last bucket includes the maximum. Non-numeric values are skipped. Stats must
+// be collected with WithStats for the histogram to be populated.
+func (w *Writer) Histogram(column string, buckets int) map[string]int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	hist := map[string]int{}
+	s, ok := w.stats[column]
+	if !ok || s.Numeric == 0 || buckets <= 0 {
+		return hist
+	}
+	if s.Min == s.Max {
+		hist[fmt.Sprintf("[%g, %g]", s.Min, s.Max)] = s.Numeric
+		return hist
+	}
+	width := (s.Max - s.Min) / float64(buckets)
+	labels := make([]string, buckets)
+	for i := range labels {
+		lo := s.Min + float64(i)*width
+		if i == buckets-1 {
+			labels[i] = fmt.Sprintf("[%g, %g]", lo, s.Max)
+			continue
+		}
+		labels[i] = fmt.Sprintf("[%g, %g)", lo, lo+width)
+	}
+	for _, v := range s.values {
+		i := int((v - s.Min) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		hist[labels[i]]++
+	}
+	return hist
+}
@@ -0,0 +1,133 @@
+package multiwriter
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggregateKeepsNoValues(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"sum", "avg", "min", "max", "count"}, CSVFormat,
+		WithAggregate("sum", Sum),
+		WithAggregate("avg", Avg),
+		WithAggregate("min", Min),
+		WithAggregate("max", Max),
+		WithAggregate("count", Count),
+	)
+	for _, v := range []string{"4", "2", "x", "6"} {
+		if err := w.Write([]string{v, v, v, v, v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for col, s := range w.stats {
+		if s.values != nil {
+			t.Errorf("got %d values kept for %s, want none", len(s.values), col)
+		}
+	}
+	if w.footerValues != nil && len(w.footerValues["sum"]) > 0 {
+		t.Errorf("got footer values kept for sum, want none")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "sum,avg,min,max,count\n4,4,4,4,4\n2,2,2,2,2\nx,x,x,x,x\n6,6,6,6,6\n12,4,2,6,3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHistogramKeepsValues(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"n"}, CSVFormat, WithStats())
+	for _, v := range []string{"0", "1", "3", "4"} {
+		w.Write([]string{v})
+	}
+	hist := w.Histogram("n", 2)
+	if hist["[0, 2)"] != 2 || hist["[2, 4]"] != 2 {
+		t.Errorf("got %v, want 2 values in each bucket", hist)
+	}
+}
+
+func TestFooterFuncStats(t *testing.T) {
+	var gotCount int
+	var gotStats Stats
+	footer := func(rowCount int, stats map[string]Stats) []string {
+		gotCount, gotStats = rowCount, stats["amount"]
+		s := stats["amount"]
+		return []string{"total", formatNumber(s.Sum), formatNumber(s.Mean())}
+	}
+	got := writeRecords(t, []string{"name", "amount", "mean"}, CSVFormat,
+		[][]string{{"a", "4", ""}, {"b", "n/a", ""}, {"c", "-1", ""}, {"d", "3", ""}},
+		WithFooterFunc(footer))
+	if want := "name,amount,mean\na,4,\nb,n/a,\nc,-1,\nd,3,\ntotal,6,2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if gotCount != 4 {
+		t.Errorf("got row count %d, want 4", gotCount)
+	}
+	want := Stats{Count: 4, Numeric: 3, Sum: 6, Min: -1, Max: 4}
+	if !reflect.DeepEqual(gotStats, want) {
+		t.Errorf("got stats %+v, want %+v", gotStats, want)
+	}
+}
+
+func TestFooter(t *testing.T) {
+	join := func(values []string) string { return strings.Join(values, "+") }
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name", "n"}, CSVFormat, WithFooter("n", join),
+		WithFooterFunc(func(int, map[string]Stats) []string { return []string{"total", "overridden"} }))
+	w.Write([]string{"a", "1"})
+	w.Flush()
+	w.Write([]string{"b", "2"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// values accumulate across flushes and WithFooter cells take precedence
+	// over the footer func
+	if got, want := buf.String(), "name,n\na,1\ntotal,1\nb,2\ntotal,1+2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAggregateFooter(t *testing.T) {
+	last := AggFunc(func(values []float64) float64 { return values[len(values)-1] })
+	records := [][]string{{"a", "1.5", "3"}, {"b", "x", "5"}, {"c", "2", "4"}}
+	opts := []Option{
+		WithFooterFunc(func(int, map[string]Stats) []string { return []string{"total"} }),
+		WithAggregate("size", Sum),
+		WithAggregate("n", last),
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		// values that don't parse are skipped
+		{CSVFormat, "name,size,n\na,1.5,3\nb,x,5\nc,2,4\ntotal,3.5,4\n"},
+		{TableFormat, `+-------+------+---+
+| NAME  | SIZE | N |
++-------+------+---+
+| a     |  1.5 | 3 |
+| b     | x    | 5 |
+| c     |    2 | 4 |
++-------+------+---+
+| TOTAL | 3.5  | 4 |
++-------+------+---+
+`},
+		// other formats have no footer
+		{JSONFormat, "[\n{\"name\":\"a\",\"size\":\"1.5\",\"n\":\"3\"},\n{\"name\":\"b\",\"size\":\"x\",\"n\":\"5\"},\n{\"name\":\"c\",\"size\":\"2\",\"n\":\"4\"}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := writeRecords(t, []string{"name", "size", "n"}, tt.format, records, opts...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// a column without values aggregates to 0
+	if got, want := writeRecords(t, []string{"n"}, CSVFormat, nil, WithAggregate("n", Avg)), "n\n0\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package multiwriter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kataras/tablewriter"
+)
+
+// streamTable holds the state of a Table rendered one row at a time
+type streamTable struct {
+	preset map[string]int
+	widths []int
+	open   bool
+}
+
+// WithStreaming renders the Table format one row at a time, writing each row
+// to the output as soon as it is written instead of when the writer flushes,
+// for long-running commands. Columns are as wide as given by widths, or else
+// as wide as their header or their value in the first row, whichever is
+// wider, and wider values are shortened according to WithTruncatePolicy.
+// Each Flush closes the table, so the next row starts a new one.
+func WithStreaming(widths map[string]int) Option {
+	return func(w *Writer) {
+		w.stream = &streamTable{preset: widths}
+	}
+}
+
+// writeStreamRow writes values as a table row to the output, opening the
+// table first if needed, and flushes the output pipe or func sink. Separator rows don't count towards repeating the
+// header.
+func (w *Writer) writeStreamRow(values []string, separator bool) error {
+	var b strings.Builder
+	due := !separator && w.headerDue()
+	if !w.stream.open {
+		w.openStream(&b, values)
+	} else if due {
+		w.renderStreamCells(&b, w.streamHeader(), false)
+	}
+	w.renderStreamCells(&b, w.tableValues(values), true)
+	if _, err := w.strw.WriteString(b.String()); err != nil {
+		return err
+	}
+	if err := w.strw.Flush(); err != nil {
+		return err
+	}
+	w.flushOutput()
+	return nil
+}
+
+// openStream computes the column widths from the header and the first row's
+// values and renders the top of the table into b
+func (w *Writer) openStream(b *strings.Builder, values []string) {
+	header := w.streamHeader()
+	w.stream.widths = make([]int, len(w.columns))
+	for i, col := range w.columns {
+		if width, ok := w.stream.preset[col]; ok {
+			w.stream.widths[i] = width
+			continue
+		}
+		width := 1
+		if !w.noHeader {
+			width = tablewriter.DisplayWidth(header[i])
+		}
+		if i < len(values) {
+			for _, line := range strings.Split(values[i], "\n") {
+				if n := tablewriter.DisplayWidth(line); n > width {
+					width = n
+				}
+			}
+		}
+		w.stream.widths[i] = width
+	}
+	w.stream.open = true
+	if !w.tableStyle.noBorder {
+		w.renderStreamBorder(b)
+	}
+	if !w.noHeader {
+		w.renderStreamCells(b, header, false)
+		w.renderStreamBorder(b)
+	}
+}
+
+// closeStream renders the footer, if any, and the bottom of the table to the
+// output
+func (w *Writer) closeStream(footer []string) error {
+	if !w.stream.open {
+		return nil
+	}
+	var b strings.Builder
+	if footer != nil {
+		w.renderStreamBorder(&b)
+		titled := make([]string, len(footer))
+		for i, v := range footer {
+			titled[i] = tablewriter.Title(v)
+		}
+		w.renderStreamCells(&b, titled, false)
+	}
+	if !w.tableStyle.noBorder {
+		w.renderStreamBorder(&b)
+	}
+	w.stream.open = false
+	w.sinceHeader = 0
+	_, err := w.strw.WriteString(b.String())
+	return err
+}
+
+// streamHeader returns the titled header of the streamed table
+func (w *Writer) streamHeader() []string {
+	header := w.headerNames()
+	for i, col := range header {
+		header[i] = tablewriter.Title(col)
+	}
+	return header
+}
+
+// renderStreamBorder renders a border line of the streamed table into b
+func (w *Writer) renderStreamBorder(b *strings.Builder) {
+	b.WriteString("+")
+	for _, width := range w.stream.widths {
+		b.WriteString(strings.Repeat("-", width+2) + "+")
+	}
+	b.WriteString("\n")
+}
+
+// renderStreamCells renders values as a row of the streamed table into b,
+// spanning several lines if any value is wrapped or has newlines. Records are
+// aligned and colored as set by the table options, while the header and
+// footer are centered.
+func (w *Writer) renderStreamCells(b *strings.Builder, values []string, record bool) {
+	aligns, _ := w.tableAlignments()
+	cells := make([][]string, len(w.stream.widths))
+	height := 1
+	for i, width := range w.stream.widths {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		cells[i] = strings.Split(limitWidth(v, width, w.truncate), "\n")
+		if len(cells[i]) > height {
+			height = len(cells[i])
+		}
+	}
+	edge := "|"
+	if w.tableStyle.noBorder {
+		edge = ""
+	}
+	for line := 0; line < height; line++ {
+		b.WriteString(edge)
+		for i, width := range w.stream.widths {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			cell := ""
+			if line < len(cells[i]) {
+				cell = cells[i][line]
+			}
+			align := aligns[i]
+			if !record {
+				align = tablewriter.ALIGN_CENTER
+			}
+			padded := padCell(cell, width, align)
+			if codes := w.tableStyle.colors[w.columns[i]]; record && len(codes) > 0 && !w.noColor {
+				padded = "\x1b[" + sgrSequence(codes) + "m" + padded + "\x1b[0m"
+			}
+			b.WriteString(" " + padded + " ")
+		}
+		b.WriteString(edge + "\n")
+	}
+}
+
+// padCell pads cell to width according to align, aligning numbers to the
+// right by default as tables do
+func padCell(cell string, width, align int) string {
+	if align == tablewriter.ALIGN_DEFAULT {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(cell), 64); err == nil {
+			align = tablewriter.ALIGN_RIGHT
+		}
+	}
+	switch align {
+	case tablewriter.ALIGN_RIGHT:
+		return tablewriter.PadLeft(cell, " ", width)
+	case tablewriter.ALIGN_CENTER:
+		return tablewriter.Pad(cell, " ", width)
+	}
+	return tablewriter.PadRight(cell, " ", width)
+}
+
+// sgrSequence joins SGR codes into the parameters of an escape sequence
+func sgrSequence(codes []int) string {
+	params := make([]string, len(codes))
+	for i, code := range codes {
+		params[i] = strconv.Itoa(code)
+	}
+	return strings.Join(params, ";")
+}
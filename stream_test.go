@@ -0,0 +1,28 @@
+package multiwriter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamingFlushesEachRow(t *testing.T) {
+	var chunks []string
+	w := NewFunc(func(rendered []byte) error {
+		chunks = append(chunks, string(rendered))
+		return nil
+	}, []string{"id", "name"}, TableFormat, WithStreaming(nil))
+	for i, name := range []string{"alice", "bob"} {
+		if err := w.Write([]string{"1", name}); err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) != i+1 {
+			t.Fatalf("got %d chunks after %d rows, want %d", len(chunks), i+1, i+1)
+		}
+		if !strings.Contains(chunks[i], name) {
+			t.Errorf("got chunk %q, want the row for %s", chunks[i], name)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package multiwriter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WriteStruct writes the exported fields of the struct v, or of the struct v
+// points to, as a record. Fields are matched to columns by their
+// `multiwriter:"column"` tag or, without one, by their name ignoring case, and
+// converted to strings with fmt.Sprint. Fields tagged "-" or without a
+// matching column are skipped and columns without a matching field are
+// written empty. Nil pointer fields are written empty.
+func (w *Writer) WriteStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("expected a struct or pointer to struct, got %T", v)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	columns := w.recordColumns()
+	record := make([]string, len(columns))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, ok := field.Tag.Lookup("multiwriter")
+		if name == "-" {
+			continue
+		}
+		for j, col := range columns {
+			if (ok && col == name) || (!ok && strings.EqualFold(col, field.Name)) {
+				record[j] = structValue(rv.Field(i))
+			}
+		}
+	}
+	return w.write(record)
+}
+
+// WriteMap writes the values of m as a record, matching its keys to columns.
+// Keys without a matching column are skipped and columns without a matching
+// key are written empty.
+func (w *Writer) WriteMap(m map[string]string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	columns := w.recordColumns()
+	record := make([]string, len(columns))
+	for i, col := range columns {
+		record[i] = m[col]
+	}
+	return w.write(record)
+}
+
+// structValue converts a struct field to a string, dereferencing pointers
+func structValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
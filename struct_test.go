@@ -0,0 +1,70 @@
+package multiwriter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type structUser struct {
+	ID       int     `multiwriter:"id"`
+	Name     string  // matched by name ignoring case
+	Email    *string `multiwriter:"email"`
+	Password string  `multiwriter:"-"`
+	Extra    string  // no matching column
+	note     string
+}
+
+func TestWriteStruct(t *testing.T) {
+	email := "a@example.com"
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name", "email", "password", "note"}, CSVFormat)
+	users := []interface{}{
+		structUser{ID: 1, Name: "alice", Email: &email, Password: "secret", Extra: "x", note: "hidden"},
+		&structUser{ID: 2, Name: "bob"},
+	}
+	for _, u := range users {
+		if err := w.WriteStruct(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []interface{}{"alice", (*structUser)(nil), 3} {
+		err := w.WriteStruct(v)
+		if err == nil || !strings.Contains(err.Error(), "expected a struct or pointer to struct") {
+			t.Errorf("got %v for %T, want a struct error", err, v)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// tagged "-" and unexported fields are left empty, as is a nil pointer
+	want := "id,name,email,password,note\n1,alice,a@example.com,,\n2,bob,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteMap(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id", "name", "email"}, CSVFormat)
+	maps := []map[string]string{
+		{"id": "1", "name": "alice", "email": "a@example.com"},
+		// missing keys are written empty and unknown keys skipped
+		{"name": "bob", "id": "2", "role": "admin"},
+	}
+	for _, m := range maps {
+		if err := w.WriteMap(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id,name,email\n1,alice,a@example.com\n2,bob,\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := w.WriteMap(maps[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,130 @@
+package multiwriter
+
+import "github.com/kataras/tablewriter"
+
+// Alignment is the horizontal alignment of a Table column
+type Alignment int
+
+const (
+	// AlignDefault aligns numbers to the right and other values to the left
+	AlignDefault Alignment = iota
+	// AlignLeft aligns values to the left
+	AlignLeft
+	// AlignCenter centers values
+	AlignCenter
+	// AlignRight aligns values to the right
+	AlignRight
+)
+
+// tableStyle holds the appearance of the Table format set by options
+type tableStyle struct {
+	aligns   map[string]Alignment
+	noBorder bool
+	colors   map[string][]int
+	wrap     *int
+	// colored is set once the colors are applied to the writer's table
+	colored bool
+}
+
+// WithTableAlignment aligns the column in the Table format, overriding the
+// right alignment of columns declared as Int or Float
+func WithTableAlignment(column string, align Alignment) Option {
+	return func(w *Writer) {
+		if w.tableStyle.aligns == nil {
+			w.tableStyle.aligns = map[string]Alignment{}
+		}
+		w.tableStyle.aligns[column] = align
+	}
+}
+
+// WithBorders sets whether the Table format draws the outer border. The
+// lines separating the header and footer are always drawn.
+func WithBorders(border bool) Option {
+	return func(w *Writer) {
+		w.tableStyle.noBorder = !border
+	}
+}
+
+// WithColumnColor colors the column's values in the Table format with the
+// given SGR codes, e.g. tablewriter.FgRedColor and tablewriter.Bold, subject
+// to WithColor. Colors are applied along with the header, so they have no
+// effect with WithNoHeader or in Render.
+func WithColumnColor(column string, codes ...int) Option {
+	return func(w *Writer) {
+		if w.tableStyle.colors == nil {
+			w.tableStyle.colors = map[string][]int{}
+		}
+		w.tableStyle.colors[column] = codes
+	}
+}
+
+// WithAutoWrap wraps Table cells longer than width at word boundaries. A
+// width of 0 disables wrapping. By default cells are wrapped at 30 characters.
+func WithAutoWrap(width int) Option {
+	return func(w *Writer) {
+		w.tableStyle.wrap = &width
+	}
+}
+
+// styleTable applies the table style to table
+func (w *Writer) styleTable(table *tablewriter.Table) {
+	table.SetBorder(!w.tableStyle.noBorder)
+	if wrap := w.tableStyle.wrap; wrap != nil {
+		table.SetAutoWrapText(*wrap > 0)
+		if *wrap > 0 {
+			table.SetColWidth(*wrap)
+		}
+	}
+}
+
+// tableAlignments returns the alignment of each column in the Table format
+// and whether any column isn't aligned by default
+func (w *Writer) tableAlignments() ([]int, bool) {
+	aligns := make([]int, len(w.columns))
+	set := false
+	for i, col := range w.columns {
+		if w.numericColumn(col) {
+			aligns[i] = tablewriter.ALIGN_RIGHT
+			set = true
+		}
+		switch w.tableStyle.aligns[col] {
+		case AlignLeft:
+			aligns[i] = tablewriter.ALIGN_LEFT
+		case AlignCenter:
+			aligns[i] = tablewriter.ALIGN_CENTER
+		case AlignRight:
+			aligns[i] = tablewriter.ALIGN_RIGHT
+		default:
+			continue
+		}
+		set = true
+	}
+	return aligns, set
+}
+
+// colorTable applies the colors set by WithColumnColor to the writer's table
+// once its header is set, since the table needs it to know its columns
+func (w *Writer) colorTable() {
+	if len(w.tableStyle.colors) == 0 || w.tableStyle.colored || w.pivoting() || w.noHeader || w.noColor {
+		return
+	}
+	colors := make([]tablewriter.Colors, len(w.columns))
+	for i, col := range w.columns {
+		colors[i] = w.tableStyle.colors[col]
+	}
+	w.table.SetColumnColor(colors...)
+	w.tableStyle.colored = true
+}
+
+// tableBorders returns the number of border lines that precede the records
+// of a rendered table
+func (w *Writer) tableBorders() int {
+	n := 0
+	if !w.tableStyle.noBorder {
+		n++
+	}
+	if !w.noHeader {
+		n++
+	}
+	return n
+}
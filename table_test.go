@@ -0,0 +1,218 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kataras/tablewriter"
+	"github.com/mattn/go-runewidth"
+)
+
+// checkTableWidths fails unless every line of each table in out is as wide as
+// the table's top border
+func checkTableWidths(t *testing.T, out string) {
+	t.Helper()
+	width := -1
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		n := runewidth.StringWidth(line)
+		if width < 0 {
+			width = n
+		}
+		if n != width {
+			t.Fatalf("got line %q %d wide, want %d", line, n, width)
+		}
+	}
+}
+
+func TestTableAlignedPastBufferSize(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name", "n"}, TableFormat)
+	for i := 0; i < 5000; i++ {
+		if err := w.Write([]string{"a", strconv.Itoa(i % 10)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Write([]string{"a much longer name here", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() < 10000 {
+		t.Fatalf("got %d bytes, want more than the default buffer size", buf.Len())
+	}
+	if n := strings.Count(buf.String(), " NAME "); n != 1 {
+		t.Errorf("got %d headers, want 1", n)
+	}
+	checkTableWidths(t, buf.String())
+}
+
+func TestTableStyle(t *testing.T) {
+	records := [][]string{{"alice", "5"}, {"bob", "100"}}
+	tests := []struct {
+		name    string
+		records [][]string
+		opts    []Option
+		want    string
+	}{
+		{"alignment", records, []Option{WithColumnType("n", Int), WithTableAlignment("name", AlignCenter), WithTableAlignment("n", AlignLeft)}, `+-------+-----+
+| NAME  |  N  |
++-------+-----+
+| alice | 5   |
+|  bob  | 100 |
++-------+-----+
+`},
+		{"no borders", records, []Option{WithBorders(false)}, `  NAME  |  N   
++-------+-----+
+  alice |   5  
+  bob   | 100  
+`},
+		{"wrap", [][]string{{"the quick brown fox jumps", "10"}}, []Option{WithAutoWrap(10)}, `+------------+----+
+|    NAME    | N  |
++------------+----+
+| the quick  | 10 |
+| brown fox  |    |
+| jumps      |    |
++------------+----+
+`},
+		{"no wrap", [][]string{{"the quick brown fox jumps over the lazy dog", "10"}}, []Option{WithAutoWrap(0)}, `+---------------------------------------------+----+
+|                    NAME                     | N  |
++---------------------------------------------+----+
+| the quick brown fox jumps over the lazy dog | 10 |
++---------------------------------------------+----+
+`},
+		{"color", records[:1], []Option{WithColumnColor("name", tablewriter.FgRedColor), WithColor(ColorAlways)}, "+-------+---+\n| NAME  | N |\n+-------+---+\n| \x1b[31malice\x1b[0m | 5 |\n+-------+---+\n"},
+		{"color never", records[:1], []Option{WithColumnColor("name", tablewriter.FgRedColor), WithColor(ColorNever)}, "+-------+---+\n| NAME  | N |\n+-------+---+\n| alice | 5 |\n+-------+---+\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := writeRecords(t, []string{"name", "n"}, TableFormat, tt.records, tt.opts...); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// splitTables splits out into the tables it holds, each starting with a top
+// border right after the bottom border of the previous one
+func splitTables(out string) []string {
+	var tables []string
+	lines := strings.SplitAfter(out, "\n")
+	start := 0
+	for i := 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "+") && strings.HasPrefix(lines[i-1], "+") {
+			tables = append(tables, strings.Join(lines[start:i], ""))
+			start = i
+		}
+	}
+	return append(tables, strings.Join(lines[start:], ""))
+}
+
+func TestTableBatches(t *testing.T) {
+	records := [][]string{{"a", "1"}, {"bb", "22"}, {"a much longer name", "3"}, {"d", "4"}}
+	tests := []struct {
+		name string
+		opt  Option
+		rows []int
+	}{
+		{"rows", WithMaxBufferedRows(2), []int{2, 2}},
+		{"bytes", WithMaxBufferedBytes(8), []int{2, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, []string{"name", "n"}, TableFormat, records, tt.opt)
+			tables := splitTables(got)
+			if len(tables) != len(tt.rows) {
+				t.Fatalf("got %d tables, want %d in\n%s", len(tables), len(tt.rows), got)
+			}
+			// each batch is a table of its own, with its header, its rows and
+			// its borders all lined up
+			for i, table := range tables {
+				checkTableWidths(t, table)
+				if n := strings.Count(table, " NAME "); n != 1 {
+					t.Errorf("got %d headers in table %d, want 1", n, i)
+				}
+				if n := strings.Count(table, "\n") - 4; n != tt.rows[i] {
+					t.Errorf("got %d rows in table %d, want %d", n, i, tt.rows[i])
+				}
+			}
+		})
+	}
+
+	// the first table isn't widened by the rows of the next
+	want := `+------+----+
+| NAME | N  |
++------+----+
+| a    |  1 |
+| bb   | 22 |
++------+----+
+`
+	if got := splitTables(writeRecords(t, []string{"name", "n"}, TableFormat, records, WithMaxBufferedRows(2)))[0]; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	records := [][]string{{"1", "alice", "a@example.com"}, {"2", "bob", "b@example.com"}}
+	tests := []struct {
+		name   string
+		format string
+		opts   []Option
+		want   string
+	}{
+		{"table", TableFormat, nil, `+-------+---------------+
+| FIELD |     VALUE     |
++-------+---------------+
+| id    | 1             |
+| name  | alice         |
+| email | a@example.com |
++-------+---------------+
+
++-------+---------------+
+| FIELD |     VALUE     |
++-------+---------------+
+| id    | 2             |
+| name  | bob           |
+| email | b@example.com |
++-------+---------------+
+`},
+		{"no header", TableFormat, []Option{WithNoHeader()}, `+-------+---------------+
+| id    | 1             |
+| name  | alice         |
+| email | a@example.com |
++-------+---------------+
+
++-------+---------------+
+| id    | 2             |
+| name  | bob           |
+| email | b@example.com |
++-------+---------------+
+`},
+		// only the Table format is transposed
+		{"csv", CSVFormat, nil, "id,name,email\n1,alice,a@example.com\n2,bob,b@example.com\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithTranspose()}, tt.opts...)
+			if got := writeRecords(t, []string{"id", "name", "email"}, tt.format, records, opts...); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// tables stay separated across flushes and footers are not rendered
+	var buf bytes.Buffer
+	w := New(&buf, []string{"id"}, TableFormat, WithTranspose(),
+		WithFooterFunc(func(int, map[string]Stats) []string { return []string{"total"} }))
+	w.Write([]string{"1"})
+	w.Flush()
+	w.Write([]string{"2"})
+	w.Close()
+	want := "+-------+-------+\n| FIELD | VALUE |\n+-------+-------+\n| id    | 1     |\n+-------+-------+\n\n" +
+		"+-------+-------+\n| FIELD | VALUE |\n+-------+-------+\n| id    | 2     |\n+-------+-------+\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
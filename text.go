@@ -0,0 +1,26 @@
+package multiwriter
+
+import (
+	"strings"
+	"text/template"
+)
+
+// WithTemplate renders each record of the Text format with t instead of the
+// "key: value" block, e.g. "{{.name}}\t{{.size}}\n". The template is executed
+// with a map from column names to formatted values, so columns whose names
+// aren't identifiers are reached with index, e.g. {{index . "file name"}}.
+// Values are not quoted, since the template controls the layout.
+func WithTemplate(t *template.Template) Option {
+	return func(w *Writer) {
+		w.textTemplate = t
+	}
+}
+
+// renderTextTemplate renders values into b with the Text format template
+func (w *Writer) renderTextTemplate(b *strings.Builder, values []string) error {
+	data := make(map[string]string, len(values))
+	for i, v := range values {
+		data[w.columns[i]] = v
+	}
+	return w.textTemplate.Execute(b, data)
+}
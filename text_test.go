@@ -0,0 +1,39 @@
+package multiwriter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplate(t *testing.T) {
+	// the template fails halfway through the BAD record
+	tmpl := template.Must(template.New("row").Parse(`{{.name}}	{{index . "file size"}}{{if eq .name "BAD"}}{{.name.x}}{{end}}` + "\n"))
+	var buf bytes.Buffer
+	w := New(&buf, []string{"name", "file size"}, TextFormat, WithTemplate(tmpl), WithTextHeader(true),
+		WithFormatter("name", FuncFormatter(strings.ToUpper)))
+	if err := w.Write([]string{"a", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Write([]string{"bad", "2"})
+	if err == nil || !strings.Contains(err.Error(), "record 1: error writing record to text: template: row") {
+		t.Errorf("got %v, want the template error", err)
+	}
+	if err := w.Write([]string{"b", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	// values are formatted and the failed record leaves no partial output
+	if got, want := buf.String(), "columns: name, file size\nA\t1\nB\t3\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err := w.Render([]string{"c", "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "C\t4\n"; string(got) != want {
+		t.Errorf("got %q rendered, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package multiwriter
+
+import (
+	"io"
+
+	"github.com/kataras/tablewriter"
+)
+
+// WithTranspose renders the Table format with columns as rows, as one
+// "Field | Value" table per record, for describe-style output of records with
+// many columns. Tables are separated by a blank line and footers are not
+// rendered.
+func WithTranspose() Option {
+	return func(w *Writer) {
+		w.transpose = true
+	}
+}
+
+// writeTransposed writes values to the output as a transposed table
+func (w *Writer) writeTransposed(values []string) error {
+	if w.transposed > 0 {
+		if _, err := w.strw.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	w.transposed++
+	w.renderTransposed(w.strw, values)
+	return nil
+}
+
+// renderTransposed renders values as a table with a row for each column to
+// out, with a header unless WithNoHeader is set
+func (w *Writer) renderTransposed(out io.Writer, values []string) {
+	table := tablewriter.NewWriter(out)
+	w.styleTable(table)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT})
+	if !w.noHeader {
+		table.SetHeader([]string{"Field", "Value"})
+	}
+	values = w.tableValues(values)
+	for i, name := range w.headerNames() {
+		table.Append([]string{name, values[i]})
+	}
+	table.Render()
+}
@@ -0,0 +1,124 @@
+package multiwriter
+
+import (
+	"strings"
+
+	"github.com/kataras/tablewriter"
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// TruncatePolicy is how values wider than their column's maximum width are
+// shortened
+type TruncatePolicy int
+
+const (
+	// TruncateEllipsis cuts values and marks the cut with an ellipsis
+	TruncateEllipsis TruncatePolicy = iota
+	// TruncateWrap wraps values onto several lines at word boundaries,
+	// cutting words that are wider than the column
+	TruncateWrap
+	// TruncateCut cuts values at the maximum width
+	TruncateCut
+)
+
+// ellipsis marks where a value was cut by TruncateEllipsis
+const ellipsis = "…"
+
+// WithMaxColumnWidth limits the values of the column to width terminal
+// cells, e.g. to keep long descriptions from blowing up a table. Values are
+// shortened after formatting according to the policy set by
+// WithTruncatePolicy, in every format. Each line of a multi-line value is
+// limited separately.
+func WithMaxColumnWidth(column string, width int) Option {
+	return func(w *Writer) {
+		if w.maxWidths == nil {
+			w.maxWidths = map[string]int{}
+		}
+		w.maxWidths[column] = width
+	}
+}
+
+// WithTruncatePolicy sets how values wider than the width set by
+// WithMaxColumnWidth are shortened. The default is TruncateEllipsis.
+func WithTruncatePolicy(policy TruncatePolicy) Option {
+	return func(w *Writer) {
+		w.truncate = policy
+	}
+}
+
+// limitWidth shortens each line of value to at most width cells according to
+// policy
+func limitWidth(value string, width int, policy TruncatePolicy) string {
+	if width < 0 {
+		width = 0
+	}
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		if tablewriter.DisplayWidth(line) <= width {
+			continue
+		}
+		switch policy {
+		case TruncateWrap:
+			lines[i] = wrapLine(line, width)
+		case TruncateCut:
+			lines[i] = cutWidth(line, width)
+		default:
+			if width == 0 {
+				lines[i] = ""
+				continue
+			}
+			lines[i] = cutWidth(line, width-runewidth.StringWidth(ellipsis)) + ellipsis
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine wraps line at spaces onto lines of at most width cells, cutting
+// words wider than width
+func wrapLine(line string, width int) string {
+	if width == 0 {
+		return ""
+	}
+	var lines []string
+	cur, curWidth := "", 0
+	for _, word := range strings.Fields(line) {
+		for runewidth.StringWidth(word) > width {
+			if cur != "" {
+				lines = append(lines, cur)
+				cur, curWidth = "", 0
+			}
+			part := cutWidth(word, width)
+			if part == "" {
+				break
+			}
+			lines = append(lines, part)
+			word = word[len(part):]
+		}
+		n := runewidth.StringWidth(word)
+		switch {
+		case cur == "":
+			cur, curWidth = word, n
+		case curWidth+1+n <= width:
+			cur, curWidth = cur+" "+word, curWidth+1+n
+		default:
+			lines = append(lines, cur)
+			cur, curWidth = word, n
+		}
+	}
+	if cur != "" {
+		lines = append(lines, cur)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cutWidth returns the longest prefix of s that is at most width cells wide
+func cutWidth(s string, width int) string {
+	n := 0
+	for i, r := range s {
+		n += runewidth.RuneWidth(r)
+		if n > width {
+			return s[:i]
+		}
+	}
+	return s
+}
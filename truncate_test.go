@@ -0,0 +1,51 @@
+package multiwriter
+
+import "testing"
+
+func TestMaxColumnWidth(t *testing.T) {
+	records := [][]string{{"short"}, {"the quick brown fox"}, {"日本語テキスト"}, {"abcdefghijkl"}, {"line one\nok"}}
+	tests := []struct {
+		name   string
+		policy TruncatePolicy
+		want   string
+	}{
+		// wide runes count as two cells and each line is limited separately
+		{"ellipsis", TruncateEllipsis, "note\nshort\nthe qu…\n日本語…\nabcdef…\n\"line o…\nok\"\n"},
+		{"wrap", TruncateWrap, "note\nshort\n\"the\nquick\nbrown\nfox\"\n\"日本語\nテキス\nト\"\n\"abcdefg\nhijkl\"\n\"line\none\nok\"\n"},
+		{"cut", TruncateCut, "note\nshort\nthe qui\n日本語\nabcdefg\n\"line on\nok\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeRecords(t, []string{"note"}, CSVFormat, records, WithMaxColumnWidth("note", 7), WithTruncatePolicy(tt.policy))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// values are limited after formatting
+	got := writeRecords(t, []string{"id", "note"}, JSONFormat, [][]string{{"1", "日本語テキスト"}},
+		WithMaxColumnWidth("note", 7), WithFormatter("note", FuncFormatter(func(s string) string { return "<" + s + ">" })))
+	if want := "[\n{\"id\":\"1\",\"note\":\"<日本…\"}\n]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = writeRecords(t, []string{"id", "note"}, TableFormat, [][]string{{"1", "日本語テキスト"}, {"2", "the quick brown fox"}},
+		WithMaxColumnWidth("note", 7), WithTruncatePolicy(TruncateWrap))
+	want := `+----+--------+
+| ID |  NOTE  |
++----+--------+
+|  1 | 日本語 |
+|    | テキス |
+|    | ト     |
+|  2 | the    |
+|    | quick  |
+|    | brown  |
+|    | fox    |
++----+--------+
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	checkTableWidths(t, got)
+}
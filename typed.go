@@ -0,0 +1,86 @@
+package multiwriter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kataras/tablewriter"
+)
+
+// Column is a column name along with the type of data it holds
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// TypedFormatter is a Formatter that formats values parsed according to their
+// column's declared type: int64 for Int, float64 for Float, bool for Bool and
+// string otherwise. When a registered formatter implements it, FormatValue is
+// called instead of Format unless the value doesn't parse as the column's
+// type.
+type TypedFormatter interface {
+	Formatter
+	FormatValue(value interface{}) string
+}
+
+// TypedFuncFormatter wraps a user-defined function to apply formatting to
+// typed values
+type TypedFuncFormatter func(value interface{}) string
+
+// Format formats the value by calling the external function with the string
+func (tf TypedFuncFormatter) Format(value string) string {
+	return tf(value)
+}
+
+// FormatValue formats the value by calling the external function
+func (tf TypedFuncFormatter) FormatValue(value interface{}) string {
+	return tf(value)
+}
+
+// WithColumnTypes declares the type of data held by each of the columns, as
+// WithColumnType does for a single column
+func WithColumnTypes(columns ...Column) Option {
+	return func(w *Writer) {
+		for _, col := range columns {
+			w.types[col.Name] = col.Type
+		}
+	}
+}
+
+// parseTyped parses value according to t, returning whether it parsed
+func parseTyped(t ColumnType, value string) (interface{}, bool) {
+	value = strings.TrimSpace(value)
+	switch t {
+	case Int:
+		i, err := strconv.ParseInt(value, 10, 64)
+		return i, err == nil
+	case Float:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	case Bool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	}
+	return value, true
+}
+
+// numericColumn returns whether the column is declared as Int or Float
+func (w *Writer) numericColumn(column string) bool {
+	t := w.types[column]
+	return t == Int || t == Float
+}
+
+// newTable returns a styled Table writing to the output, with columns
+// declared as Int or Float aligned to the right unless aligned otherwise
+func (w *Writer) newTable() *tablewriter.Table {
+	table := tablewriter.NewWriter(w.basew)
+	w.tableStyle.colored = false
+	w.styleTable(table)
+	if w.pivoting() {
+		return table
+	}
+	if aligns, ok := w.tableAlignments(); ok {
+		table.SetColumnAlignment(aligns)
+	}
+	return table
+}
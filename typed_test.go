@@ -0,0 +1,44 @@
+package multiwriter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTypedJSONNumbers(t *testing.T) {
+	records := [][]string{{"alice", "5", "1.5", "true"}, {"bob", "007", "n/a", "x"}}
+	got := writeRecords(t, []string{"name", "n", "f", "ok"}, JSONFormat, records,
+		WithColumnTypes(Column{"n", Int}, Column{"f", Float}, Column{"ok", Bool}))
+	// values that aren't valid JSON numbers or booleans stay strings
+	want := "[\n{\"name\":\"alice\",\"n\":5,\"f\":1.5,\"ok\":true},\n{\"name\":\"bob\",\"n\":\"007\",\"f\":\"n/a\",\"ok\":\"x\"}\n]\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTypedTableAlignment(t *testing.T) {
+	records := [][]string{{"alice", "5", "1.5"}, {"bob", "100", "22.25"}}
+	got := writeRecords(t, []string{"name", "n", "f"}, TableFormat, records,
+		WithColumnTypes(Column{"n", Int}, Column{"f", Float}))
+	want := `+-------+-----+-------+
+| NAME  |  N  |   F   |
++-------+-----+-------+
+| alice |   5 |   1.5 |
+| bob   | 100 | 22.25 |
++-------+-----+-------+
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTypedFormatter(t *testing.T) {
+	typeName := TypedFuncFormatter(func(v interface{}) string { return fmt.Sprintf("%T", v) })
+	got := writeRecords(t, []string{"n", "f", "b", "s"}, CSVFormat, [][]string{{"5", "1.5", "true", "x"}, {"x", "y", "z", "w"}},
+		WithColumnTypes(Column{"n", Int}, Column{"f", Float}, Column{"b", Bool}),
+		WithFormatter("n", typeName), WithFormatter("f", typeName), WithFormatter("b", typeName), WithFormatter("s", typeName))
+	// values that don't parse as the column's type are passed to Format
+	if want := "n,f,b,s\nint64,float64,bool,string\nstring,string,string,string\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
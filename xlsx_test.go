@@ -0,0 +1,87 @@
+package multiwriter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// xlsxCell is a cell of a worksheet as the XLSX format writes it
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Style  string `xml:"s,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+func TestXLSX(t *testing.T) {
+	out := writeRecords(t, []string{"code", "n", "note"}, XLSXFormat,
+		[][]string{{"007", "5", "a & b"}, {"1e3", "n/a", "bell\x07"}}, WithColumnType("n", Int))
+	zr, err := zip.NewReader(bytes.NewReader([]byte(out)), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet []byte
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every part of the workbook must be well-formed XML
+		d := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("got invalid XML in %s: %v", f.Name, err)
+			}
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = content
+		}
+	}
+	if sheet == nil {
+		t.Fatal("got no worksheet")
+	}
+
+	var ws struct {
+		Rows []struct {
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &ws); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]xlsxCell
+	for _, r := range ws.Rows {
+		got = append(got, r.Cells)
+	}
+	// the header is bold, text keeps its leading zeros, and only values of
+	// numeric columns that parse are written as numbers
+	want := [][]xlsxCell{
+		{{"A1", "1", "inlineStr", "", "code"}, {"B1", "1", "inlineStr", "", "n"}, {"C1", "1", "inlineStr", "", "note"}},
+		{{"A2", "", "inlineStr", "", "007"}, {"B2", "", "", "5", ""}, {"C2", "", "inlineStr", "", "a & b"}},
+		{{"A3", "", "inlineStr", "", "1e3"}, {"B3", "", "inlineStr", "", "n/a"}, {"C3", "", "inlineStr", "", "bell"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("got %s for column %d, want %s", got, i, want)
+		}
+	}
+}
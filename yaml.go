@@ -0,0 +1,48 @@
+package multiwriter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// yamlPlainKey matches keys that can be written as plain YAML scalars, unless
+// they are reserved
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// yamlReserved holds the lowercased plain scalars that YAML 1.1 or 1.2 read as
+// null or booleans
+var yamlReserved = map[string]bool{
+	"null": true, "true": true, "false": true,
+	"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true,
+}
+
+// yamlKey returns key as a plain YAML scalar if it would be read back as the
+// same string, and double-quoted otherwise, e.g. for "no", "~" or "1e3"
+func yamlKey(key string) string {
+	if !yamlPlainKey.MatchString(key) || yamlReserved[strings.ToLower(key)] {
+		return jsonString(key)
+	}
+	return key
+}
+
+// writeYAML writes the row as an item of a YAML sequence
+func (w *Writer) writeYAML(r row) error {
+	w.renderYAML(&w.str, r.values)
+	_, err := w.strw.WriteString(w.str.String())
+	w.str.Reset()
+	return err
+}
+
+// renderYAML renders values into b as a YAML sequence item mapping column
+// names to values. Values are double-quoted, which YAML shares with JSON, and
+// typed values are left plain as in the JSON format.
+func (w *Writer) renderYAML(b *strings.Builder, values []string) {
+	for i, v := range values {
+		if i == 0 {
+			b.WriteString("- ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(yamlKey(w.columns[i]) + ": " + w.jsonValue(w.columns[i], v) + "\n")
+	}
+}
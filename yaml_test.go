@@ -0,0 +1,36 @@
+package multiwriter
+
+import "testing"
+
+func TestYAMLKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"name", "name"},
+		{"user_id", "user_id"},
+		{"null", `"null"`},
+		{"Null", `"Null"`},
+		{"NULL", `"NULL"`},
+		{"~", `"~"`},
+		{"true", `"true"`},
+		{"False", `"False"`},
+		{"yes", `"yes"`},
+		{"No", `"No"`},
+		{"on", `"on"`},
+		{"OFF", `"OFF"`},
+		{"y", `"y"`},
+		{"N", `"N"`},
+		{"123", `"123"`},
+		{"1.5", `"1.5"`},
+		{"-1", `"-1"`},
+		{"1e3", `"1e3"`},
+		{"0x1F", `"0x1F"`},
+		{".inf", `".inf"`},
+		{"first name", `"first name"`},
+	}
+	for _, tt := range tests {
+		if got := yamlKey(tt.key); got != tt.want {
+			t.Errorf("yamlKey(%q) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+}